@@ -0,0 +1,80 @@
+// Replay steps the bot through a game recorded with APP_SSE_RECORD_FILE, to reproduce odd games locally:
+//
+//	go run ./cmd/replay -bot <bot id> -game <game id> [-level 2] [-width 7 -height 6] events.jsonl
+//
+// It prints every event, the board before each decision of the engine and the columns where the engine
+// decides differently than the bot did. The recording must contain the game from the start.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gaming-platform/connect-four-bot/internal/engine"
+	engine_marein "github.com/gaming-platform/connect-four-bot/internal/engine/marein"
+	engine_negamax "github.com/gaming-platform/connect-four-bot/internal/engine/negamax"
+	engine_random "github.com/gaming-platform/connect-four-bot/internal/engine/random"
+	"github.com/gaming-platform/connect-four-bot/internal/sse"
+)
+
+func main() {
+	botId := flag.String("bot", "", "the id of the bot whose game is replayed")
+	gameId := flag.String("game", "", "the id of the game to replay")
+	level := flag.Int("level", 2, "the level of the engine, see APP_LEVEL")
+	width := flag.Int("width", 7, "the width of the board")
+	height := flag.Int("height", 6, "the height of the board")
+	flag.Parse()
+	if *botId == "" || *gameId == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	calculateNextMove, err := newEngine(*level)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	recording, err := sse.ReadRecording(file)
+	_ = file.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mismatches, err := replay(context.Background(), os.Stdout, recording, replayOptions{
+		BotId:             *botId,
+		GameId:            *gameId,
+		Width:             *width,
+		Height:            *height,
+		CalculateNextMove: calculateNextMove,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("%d mismatches\n", mismatches)
+	if mismatches > 0 {
+		os.Exit(1)
+	}
+}
+
+func newEngine(level int) (engine.CalculateNextMove, error) {
+	switch level {
+	case 0:
+		return engine_random.CalculateNextMove, nil
+	case 1:
+		return engine_marein.CreateCalculateNextMove(engine_marein.Options{ForkCreationProbability: 75}), nil
+	case 2:
+		return engine_marein.CreateCalculateNextMove(engine_marein.Options{ForkCreationProbability: 100}), nil
+	case 3:
+		return engine_negamax.NewEngine(engine_negamax.Options{}).CalculateNextMove, nil
+	default:
+		return nil, fmt.Errorf("invalid level %d", level)
+	}
+}
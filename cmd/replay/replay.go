@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	chatv1 "github.com/gaming-platform/api/go/chat/v1"
+	connectfourv1 "github.com/gaming-platform/api/go/connectfour/v1"
+	"github.com/gaming-platform/connect-four-bot/internal/bot"
+	"github.com/gaming-platform/connect-four-bot/internal/chat"
+	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
+	"github.com/gaming-platform/connect-four-bot/internal/engine"
+	"github.com/gaming-platform/connect-four-bot/internal/rpcclient"
+	"github.com/gaming-platform/connect-four-bot/internal/sse"
+	"google.golang.org/protobuf/proto"
+)
+
+type replayOptions struct {
+	BotId             string
+	GameId            string
+	Width             int
+	Height            int
+	CalculateNextMove engine.CalculateNextMove
+}
+
+// replay feeds the recorded events of the game to bot.PlayThrough and writes the board and the engine's decisions
+// at each step to out. It returns the number of recorded moves of the bot the engine decided differently.
+func replay(ctx context.Context, out io.Writer, recording []sse.RecordedEvent, options replayOptions) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p := &platform{
+		out:        out,
+		botId:      options.BotId,
+		width:      options.Width,
+		height:     options.Height,
+		calculated: make(chan struct{}),
+	}
+	events := make(injectedEvents)
+	done := make(chan error, 1)
+	go func() {
+		done <- bot.PlayThrough(
+			ctx,
+			events,
+			connectfour.NewGameService(p),
+			chat.NewChatService(p),
+			p.engine(options.CalculateNextMove),
+			nil, // Pondering doesn't change the decisions.
+			options.BotId,
+			connectfour.NewGame(options.GameId, "", "", options.Width, options.Height),
+		)
+	}()
+
+	channel := "connect-four-" + options.GameId
+	for _, recorded := range recording {
+		if recorded.Channel != channel {
+			continue
+		}
+
+		event, _, err := sse.ParseEvent(recorded.Data)
+		if event == nil && err == nil {
+			p.printf("#%s skipped unknown event %q\n", recorded.Id, recorded.Data)
+			continue
+		} else if err != nil {
+			event = sse.Gap{Channel: channel, Cause: err} // Like the clients do.
+		}
+
+		p.printf("#%s %T %+v\n", recorded.Id, event, event)
+		calculations := p.apply(options.GameId, event)
+
+		// The barrier is ignored by the bot. Once it's taken, the event before is handled.
+		for _, res := range []sse.ConnectChannelResult{{Event: event}, {Event: struct{}{}}} {
+			select {
+			case events <- res:
+			case err := <-done:
+				return p.result(err)
+			}
+		}
+
+		// The bot calculates its moves in the background, wait for it to keep the output in order.
+		if err := p.waitForCalculations(calculations, done); err != nil {
+			return p.result(err)
+		}
+	}
+
+	cancel() // The recording ended before the game.
+
+	return p.result(<-done)
+}
+
+// injectedEvents is an event source the replay feeds directly.
+type injectedEvents chan sse.ConnectChannelResult
+
+func (i injectedEvents) Connect(context.Context, string) (chan sse.ConnectChannelResult, error) {
+	return i, nil
+}
+
+// platform answers the requests of the bot like the server would have at the current point of the recording.
+type platform struct {
+	out        io.Writer
+	botId      string
+	width      int
+	height     int
+	game       *connectfour.Game // As the server knows it, nil until the game starts and after it ended.
+	decisions  []int             // The columns the engine chose since the last recorded move of the bot.
+	mismatches int
+
+	// calculations is the number of finished calculations of the engine, calculated is closed on each.
+	calculations int
+	expected     int // The number of calculations the bot should have done at the current point.
+	calculated   chan struct{}
+	mutex        sync.Mutex
+}
+
+func (p *platform) Call(ctx context.Context, req rpcclient.Message) (rpcclient.Message, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	switch req.Name {
+	case connectfourv1.MakeMoveType:
+		return marshal(connectfourv1.MakeMoveResponseType, &connectfourv1.MakeMoveResponse{})
+	case connectfourv1.GetGamesByPlayerType:
+		resp := &connectfourv1.GetGamesByPlayerResponse{}
+		var query connectfourv1.GetGamesByPlayer
+		if err := proto.Unmarshal(req.Body, &query); err != nil {
+			return rpcclient.Message{}, err
+		}
+		if p.game != nil && query.Page <= 1 && query.State == connectfourv1.GetGamesByPlayer_STATE_RUNNING {
+			resp.Games = append(resp.Games, toProto(p.game))
+		}
+
+		return marshal(connectfourv1.GetGamesByPlayerResponseType, resp)
+	case chatv1.WriteMessageType:
+		return marshal(chatv1.WriteMessageResponseType, &chatv1.WriteMessageResponse{MessageId: "replay"})
+	default:
+		return rpcclient.Message{}, fmt.Errorf("%w: %s", rpcclient.ErrUnroutable, req.Name)
+	}
+}
+
+func (p *platform) Close() error {
+	return nil
+}
+
+// apply updates the server state with the event and compares the recorded moves of the bot with its decisions.
+// It returns the number of calculations the bot should have done once it handled the event.
+func (p *platform) apply(gameId string, event any) int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	switch e := event.(type) {
+	case sse.PlayerJoined:
+		p.game = connectfour.NewGame(gameId, "", e.RedPlayerId, p.width, p.height)
+		p.expectCalculation()
+	case sse.ChatAssigned:
+		if p.game != nil {
+			p.game.ChatId = e.ChatId
+		}
+	case sse.PlayerMoved:
+		if e.PlayerId == p.botId {
+			p.compare(e.X)
+		}
+		if p.game != nil {
+			p.game.ForceMove(e.X, e.Y, e.Color)
+			p.game.CurrentPlayerId = e.NextPlayerId
+			p.expectCalculation()
+		}
+	case sse.GameAborted, sse.GameWon, sse.GameDrawn, sse.GameTimedOut, sse.GameResigned:
+		p.game = nil
+	}
+
+	return p.expected
+}
+
+func (p *platform) expectCalculation() {
+	if p.game.CurrentPlayerId == p.botId && p.game.Outcome() == connectfour.Ongoing {
+		p.expected++
+	}
+}
+
+// waitForCalculations waits until the engine finished the given number of calculations or the bot stopped playing.
+func (p *platform) waitForCalculations(n int, done chan error) error {
+	for {
+		p.mutex.Lock()
+		calculations, calculated := p.calculations, p.calculated
+		p.mutex.Unlock()
+		if calculations >= n {
+			return nil
+		}
+
+		select {
+		case <-calculated:
+		case err := <-done:
+			done <- err // For the caller.
+			return nil
+		}
+	}
+}
+
+func (p *platform) compare(recorded int) {
+	if len(p.decisions) == 0 {
+		p.mismatches++
+		_, _ = fmt.Fprintf(p.out, "MISMATCH: the bot played column %d, the replay didn't move\n", recorded)
+		return
+	}
+
+	decided := p.decisions[len(p.decisions)-1]
+	p.decisions = nil
+	if decided != recorded {
+		p.mismatches++
+		_, _ = fmt.Fprintf(p.out, "MISMATCH: the bot played column %d, the replay chose column %d\n", recorded, decided)
+	}
+}
+
+// engine prints the board before and the decision after each calculation.
+func (p *platform) engine(calculateNextMove engine.CalculateNextMove) engine.CalculateNextMove {
+	return func(ctx context.Context, game *connectfour.Game) (int, bool) {
+		p.printf("%s", connectfour.Render(game))
+		column, ok := calculateNextMove(ctx, game)
+
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		if ok {
+			_, _ = fmt.Fprintf(p.out, "engine chooses column %d\n", column)
+			p.decisions = append(p.decisions, column)
+		} else {
+			_, _ = fmt.Fprintf(p.out, "engine found no move\n")
+		}
+		p.calculations++
+		close(p.calculated)
+		p.calculated = make(chan struct{})
+
+		return column, ok
+	}
+}
+
+func (p *platform) printf(format string, args ...any) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	_, _ = fmt.Fprintf(p.out, format, args...)
+}
+
+func (p *platform) result(err error) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.mismatches, err
+}
+
+func marshal(name string, resp proto.Message) (rpcclient.Message, error) {
+	body, err := proto.Marshal(resp)
+
+	return rpcclient.Message{Name: name, Body: body}, err
+}
+
+func toProto(game *connectfour.Game) *connectfourv1.Game {
+	moves := make([]*connectfourv1.Move, 0, game.MoveCount())
+	for _, mv := range game.Moves() {
+		moves = append(moves, &connectfourv1.Move{X: int32(mv.X), Y: int32(mv.Y), Color: int32(mv.Color)})
+	}
+
+	return &connectfourv1.Game{
+		GameId:          game.GameId,
+		ChatId:          game.ChatId,
+		CurrentPlayerId: game.CurrentPlayerId,
+		Width:           int32(game.Width),
+		Height:          int32(game.Height),
+		Moves:           moves,
+	}
+}
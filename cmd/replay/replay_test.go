@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
+	"github.com/gaming-platform/connect-four-bot/internal/sse"
+)
+
+// recordGame records a game of the bot "b" as red against "o", where both players take turns in their columns.
+func recordGame(botColumn int, opponentColumn int) []sse.RecordedEvent {
+	data := []string{
+		`ConnectFour.PlayerJoined:1:{"gameId":"g","opponentPlayerId":"o","redPlayerId":"b","yellowPlayerId":"o"}`,
+		`ConnectFour.ChatAssigned:1:{"gameId":"g","chatId":"c"}`,
+		`ConnectFour.Unknown:1:{"gameId":"g"}`,
+	}
+	for i := range 7 {
+		playerId, nextPlayerId, column, color := "b", "o", botColumn, 1
+		if i%2 == 1 {
+			playerId, nextPlayerId, column, color = "o", "b", opponentColumn, 2
+		}
+		data = append(data, fmt.Sprintf(
+			`ConnectFour.PlayerMoved:1:{"gameId":"g","x":%d,"y":%d,"color":%d,"playerId":"%s","nextPlayerId":"%s"}`,
+			column, 6-i/2, color, playerId, nextPlayerId,
+		))
+	}
+	data = append(data, `ConnectFour.GameWon:1:{"gameId":"g","winnerPlayerId":"b"}`)
+
+	recording := []sse.RecordedEvent{{Channel: "lobby", Data: `ConnectFour.GameOpened:1:{"gameId":"x"}`}}
+	for i, d := range data {
+		recording = append(recording, sse.RecordedEvent{Channel: "connect-four-g", Id: fmt.Sprint(i + 1), Data: d})
+	}
+
+	return recording
+}
+
+func firstAvailableColumn(ctx context.Context, game *connectfour.Game) (int, bool) {
+	if available := game.GetAvailableColumns(); len(available) > 0 {
+		return available[0], true
+	}
+
+	return 0, false
+}
+
+func TestReplayShowsDecisionsOfEngine(t *testing.T) {
+	var out bytes.Buffer
+	mismatches, err := replay(context.Background(), &out, recordGame(1, 2), replayOptions{
+		BotId:             "b",
+		GameId:            "g",
+		Width:             7,
+		Height:            6,
+		CalculateNextMove: firstAvailableColumn,
+	})
+	if err != nil || mismatches != 0 {
+		t.Fatalf("replay = %d, %v; want no mismatches\n%s", mismatches, err, out.String())
+	}
+
+	if n := strings.Count(out.String(), "engine chooses column 1\n"); n != 4 {
+		t.Errorf("engine chose column 1 %d times; want 4\n%s", n, out.String())
+	}
+	if !strings.Contains(out.String(), "| R Y . . . . . |\n| R Y . . . . . |\n| R Y . . . . . |\n") {
+		t.Errorf("board before the last move not shown\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "skipped unknown event") {
+		t.Errorf("unknown event not shown\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "GameOpened") {
+		t.Errorf("event of another channel shown\n%s", out.String())
+	}
+}
+
+func TestReplayReportsMismatches(t *testing.T) {
+	var out bytes.Buffer
+	mismatches, err := replay(context.Background(), &out, recordGame(3, 2), replayOptions{
+		BotId:             "b",
+		GameId:            "g",
+		Width:             7,
+		Height:            6,
+		CalculateNextMove: firstAvailableColumn,
+	})
+	if err != nil || mismatches != 4 {
+		t.Fatalf("replay = %d, %v; want 4 mismatches\n%s", mismatches, err, out.String())
+	}
+
+	if !strings.Contains(out.String(), "MISMATCH: the bot played column 3, the replay chose column 1\n") {
+		t.Errorf("mismatch not shown\n%s", out.String())
+	}
+}
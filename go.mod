@@ -5,6 +5,7 @@ go 1.25.4
 require (
 	github.com/caarlos0/env/v11 v11.3.1
 	github.com/gaming-platform/api v1.9.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/prometheus/client_golang v1.23.2
 	github.com/rabbitmq/amqp091-go v1.10.0
 	github.com/tmaxmax/go-sse v0.11.0
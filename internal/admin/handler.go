@@ -0,0 +1,24 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gaming-platform/connect-four-bot/internal/rpcclient"
+)
+
+// NewHandler serves read-only insights into the running bot, meant for operators.
+func NewHandler(circuitBreakers *rpcclient.CircuitBreakerClient) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /admin/rpc/circuit-breakers", func(w http.ResponseWriter, r *http.Request) {
+		writeJson(w, circuitBreakers.States())
+	})
+
+	return mux
+}
+
+func writeJson(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
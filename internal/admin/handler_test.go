@@ -0,0 +1,36 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gaming-platform/connect-four-bot/internal/rpcclient"
+)
+
+type nopClient struct{}
+
+func (nopClient) Call(ctx context.Context, req rpcclient.Message) (rpcclient.Message, error) {
+	return rpcclient.Message{}, nil
+}
+
+func (nopClient) Close() error {
+	return nil
+}
+
+func TestCircuitBreakers(t *testing.T) {
+	breakers := rpcclient.NewCircuitBreakerClient(nopClient{}, rpcclient.CircuitBreakerOptions{})
+	_, _ = breakers.Call(context.Background(), rpcclient.Message{Name: "ConnectFour.MakeMove"})
+
+	rec := httptest.NewRecorder()
+	NewHandler(breakers).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/rpc/circuit-breakers", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want 200", rec.Code)
+	}
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"ConnectFour":"closed"}` {
+		t.Fatalf("body = %s", got)
+	}
+}
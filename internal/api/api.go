@@ -1,30 +1,98 @@
 package api
 
 import (
-	"errors"
+	"fmt"
+	"strings"
 
 	commonv1 "github.com/gaming-platform/api/go/common/v1"
 	"google.golang.org/protobuf/proto"
 )
 
+// ViolationIdentifier is the machine-readable reason for a violation.
+// It can be used as target of errors.Is to test an *ErrorResponse for the violation.
+type ViolationIdentifier string
+
+func (v ViolationIdentifier) Error() string {
+	return string(v)
+}
+
+// Well-known identifiers the platform uses when refusing requests of the bot.
+const (
+	ViolationGameNotFound        ViolationIdentifier = "game_not_found"
+	ViolationGameNotOpen         ViolationIdentifier = "game_not_open"
+	ViolationPlayerAlreadyJoined ViolationIdentifier = "player_already_joined"
+	ViolationGameNotRunning      ViolationIdentifier = "game_not_running"
+	ViolationUnexpectedPlayer    ViolationIdentifier = "unexpected_player"
+	ViolationColumnAlreadyFilled ViolationIdentifier = "column_already_filled"
+	ViolationUsernameNotFound    ViolationIdentifier = "username_not_found"
+)
+
+type Violation struct {
+	PropertyPath string
+	Identifier   ViolationIdentifier
+	Parameters   map[string]string
+}
+
+func (v Violation) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(v.Identifier))
+	if v.PropertyPath != "" {
+		sb.WriteString(" at " + v.PropertyPath)
+	}
+	if len(v.Parameters) > 0 {
+		fmt.Fprintf(&sb, " %v", v.Parameters)
+	}
+
+	return sb.String()
+}
+
+// ErrorResponse is the platform's answer to a request it refused. It's returned as error by Call.
 type ErrorResponse struct {
-	errorResponse commonv1.ErrorResponse
+	Request    string // The name of the refused request.
+	Violations []Violation
 }
 
-func NewErrorResponse(resp []byte) (*ErrorResponse, error) {
+func NewErrorResponse(request string, resp []byte) (*ErrorResponse, error) {
 	var errResp commonv1.ErrorResponse
 	err := proto.Unmarshal(resp, &errResp)
 	if err != nil {
 		return nil, err
 	}
 
-	return &ErrorResponse{errorResponse: errResp}, nil
+	violations := make([]Violation, 0, len(errResp.GetViolations()))
+	for _, v := range errResp.GetViolations() {
+		violation := Violation{PropertyPath: v.GetPropertyPath(), Identifier: ViolationIdentifier(v.GetIdentifier())}
+		if len(v.GetParameters()) > 0 {
+			violation.Parameters = make(map[string]string, len(v.GetParameters()))
+			for _, p := range v.GetParameters() {
+				violation.Parameters[p.GetName()] = p.GetValue()
+			}
+		}
+		violations = append(violations, violation)
+	}
+
+	return &ErrorResponse{Request: request, Violations: violations}, nil
+}
+
+func (e *ErrorResponse) Error() string {
+	violations := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		violations[i] = v.String()
+	}
+
+	return fmt.Sprintf("%s refused: violations: %s", e.Request, strings.Join(violations, ", "))
+}
+
+// Is reports whether the target is a ViolationIdentifier of one of the violations.
+func (e *ErrorResponse) Is(target error) bool {
+	identifier, ok := target.(ViolationIdentifier)
+
+	return ok && e.HasViolation(string(identifier))
 }
 
 func (e *ErrorResponse) HasViolation(identifier string) bool {
-	violations := e.errorResponse.GetViolations()
-	for _, v := range violations {
-		if v.GetIdentifier() == identifier {
+	for _, v := range e.Violations {
+		if string(v.Identifier) == identifier {
 			return true
 		}
 	}
@@ -33,19 +101,9 @@ func (e *ErrorResponse) HasViolation(identifier string) bool {
 }
 
 func (e *ErrorResponse) FirstViolation() string {
-	violations := e.errorResponse.GetViolations()
-	if len(violations) == 0 {
+	if len(e.Violations) == 0 {
 		return ""
 	}
 
-	return violations[0].GetIdentifier()
-}
-
-func ErrorResponseToError(resp []byte) error {
-	errResp, err := NewErrorResponse(resp)
-	if err != nil {
-		return err
-	}
-
-	return errors.New("violations: " + errResp.FirstViolation())
+	return string(e.Violations[0].Identifier)
 }
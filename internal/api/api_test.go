@@ -0,0 +1,65 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	commonv1 "github.com/gaming-platform/api/go/common/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestErrorResponse(t *testing.T) *ErrorResponse {
+	body, err := proto.Marshal(&commonv1.ErrorResponse{
+		Violations: []*commonv1.ErrorResponse_Violation{
+			{Identifier: "player_already_joined"},
+			{
+				PropertyPath: "column",
+				Identifier:   "column_already_filled",
+				Parameters:   []*commonv1.ErrorResponse_Violation_Parameter{{Name: "column", Value: "3"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errResp, err := NewErrorResponse("ConnectFour.JoinGame", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return errResp
+}
+
+func TestErrorResponseKeepsAllViolations(t *testing.T) {
+	errResp := newTestErrorResponse(t)
+
+	if len(errResp.Violations) != 2 {
+		t.Fatalf("len(Violations) = %d; want 2", len(errResp.Violations))
+	}
+	if v := errResp.Violations[1]; v.PropertyPath != "column" || v.Parameters["column"] != "3" {
+		t.Fatalf("Violations[1] = %+v; want property path and parameters", v)
+	}
+
+	want := "ConnectFour.JoinGame refused: violations: player_already_joined, column_already_filled at column map[column:3]"
+	if got := errResp.Error(); got != want {
+		t.Fatalf("Error() = %q; want %q", got, want)
+	}
+}
+
+func TestErrorResponseIsViolation(t *testing.T) {
+	err := fmt.Errorf("join: %w", newTestErrorResponse(t))
+
+	if !errors.Is(err, ViolationPlayerAlreadyJoined) || !errors.Is(err, ViolationColumnAlreadyFilled) {
+		t.Fatal("errors.Is = false for contained violations; want true")
+	}
+	if errors.Is(err, ViolationGameNotFound) {
+		t.Fatal("errors.Is(err, ViolationGameNotFound) = true; want false")
+	}
+
+	var errResp *ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Request != "ConnectFour.JoinGame" {
+		t.Fatalf("errors.As = %v; want ErrorResponse for ConnectFour.JoinGame", errResp)
+	}
+}
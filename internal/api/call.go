@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	commonv1 "github.com/gaming-platform/api/go/common/v1"
+	"github.com/gaming-platform/connect-four-bot/internal/rpcclient"
+	"google.golang.org/protobuf/proto"
+)
+
+// UnexpectedResponseError means that the platform answered with a message the request doesn't expect.
+type UnexpectedResponseError struct {
+	Request  string
+	Response string
+}
+
+func (e *UnexpectedResponseError) Error() string {
+	return fmt.Sprintf("unexpected response %q to %s", e.Response, e.Request)
+}
+
+// Call sends the request and decodes the response of type respName into Resp.
+// If the platform refuses the request, the error is an *ErrorResponse.
+func Call[Resp any, PResp interface {
+	*Resp
+	proto.Message
+}](
+	ctx context.Context,
+	rpcClient rpcclient.RpcClient,
+	reqName string,
+	req proto.Message,
+	respName string,
+) (*Resp, error) {
+	reqBody, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := rpcClient.Call(ctx, rpcclient.Message{Name: reqName, Body: reqBody})
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.Name {
+	case respName:
+		var decoded Resp
+		if err := proto.Unmarshal(resp.Body, PResp(&decoded)); err != nil {
+			return nil, fmt.Errorf("decode %s: %w", respName, err)
+		}
+
+		return &decoded, nil
+	case commonv1.ErrorResponseType:
+		errResp, err := NewErrorResponse(reqName, resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", commonv1.ErrorResponseType, err)
+		}
+
+		return nil, errResp
+	default:
+		return nil, &UnexpectedResponseError{Request: reqName, Response: resp.Name}
+	}
+}
+
+// AsErrorResponse splits an error returned by Call into the platform's refusal and other errors.
+func AsErrorResponse(err error) (*ErrorResponse, error) {
+	var errResp *ErrorResponse
+	if errors.As(err, &errResp) {
+		return errResp, nil
+	}
+
+	return nil, err
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	commonv1 "github.com/gaming-platform/api/go/common/v1"
+	identityv1 "github.com/gaming-platform/api/go/identity/v1"
+	"github.com/gaming-platform/connect-four-bot/internal/rpcclient"
+	"google.golang.org/protobuf/proto"
+)
+
+type stubClient struct {
+	resp rpcclient.Message
+}
+
+func (c *stubClient) Call(ctx context.Context, req rpcclient.Message) (rpcclient.Message, error) {
+	return c.resp, nil
+}
+
+func (c *stubClient) Close() error {
+	return nil
+}
+
+func newStubClient(t *testing.T, name string, msg proto.Message) *stubClient {
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &stubClient{resp: rpcclient.Message{Name: name, Body: body}}
+}
+
+func callRegisterBot(client rpcclient.RpcClient) (*identityv1.RegisterBotResponse, error) {
+	return Call[identityv1.RegisterBotResponse](
+		context.Background(),
+		client,
+		identityv1.RegisterBotType,
+		&identityv1.RegisterBot{Username: "bot"},
+		identityv1.RegisterBotResponseType,
+	)
+}
+
+func TestCallDecodesResponse(t *testing.T) {
+	client := newStubClient(t, identityv1.RegisterBotResponseType, &identityv1.RegisterBotResponse{BotId: "b1"})
+
+	resp, err := callRegisterBot(client)
+	if err != nil || resp.BotId != "b1" {
+		t.Fatalf("Call = %v, %v; want BotId b1", resp, err)
+	}
+}
+
+func TestCallReturnsErrorResponse(t *testing.T) {
+	client := newStubClient(t, commonv1.ErrorResponseType, &commonv1.ErrorResponse{
+		Violations: []*commonv1.ErrorResponse_Violation{{Identifier: "username_taken"}},
+	})
+
+	_, err := callRegisterBot(client)
+	var errResp *ErrorResponse
+	if !errors.As(err, &errResp) || !errResp.HasViolation("username_taken") {
+		t.Fatalf("Call error = %v; want ErrorResponse with username_taken", err)
+	}
+}
+
+func TestCallReturnsUnexpectedResponse(t *testing.T) {
+	client := &stubClient{resp: rpcclient.Message{Name: "Identity.Unknown"}}
+
+	_, err := callRegisterBot(client)
+	var unexpected *UnexpectedResponseError
+	if !errors.As(err, &unexpected) || unexpected.Response != "Identity.Unknown" {
+		t.Fatalf("Call error = %v; want UnexpectedResponseError for Identity.Unknown", err)
+	}
+}
@@ -2,10 +2,15 @@ package bot
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"time"
 
+	connectfourv1 "github.com/gaming-platform/api/go/connectfour/v1"
 	"github.com/gaming-platform/connect-four-bot/internal/chat"
 	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
 	"github.com/gaming-platform/connect-four-bot/internal/engine"
+	"github.com/gaming-platform/connect-four-bot/internal/rpcclient"
 	"github.com/gaming-platform/connect-four-bot/internal/sse"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -16,16 +21,37 @@ var runningGamesGauge = promauto.NewGauge(prometheus.GaugeOpts{
 	Help: "The number of running games the bot is currently playing.",
 })
 
+var outcomeMismatchesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "connect_four_bot_outcome_mismatches_total",
+	Help: "The number of game endings announced by the server that disagree with the local game state.",
+}, []string{"event", "outcome"})
+
+var desyncsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "connect_four_bot_desyncs_total",
+	Help: "The number of moves announced by the server that couldn't be applied to the local game state.",
+}, []string{"reason"})
+
 type Bot interface {
 	Play(ctx context.Context) error
 }
 
-func playThrough(
+// EventSource delivers the events published to a channel until ctx is done, see sse.Client.Connect.
+// sse.Multiplexer and websocket.Client implement it.
+type EventSource interface {
+	Connect(ctx context.Context, channel string) (chan sse.ConnectChannelResult, error)
+}
+
+// PlayThrough plays the game until it ends, following the events of its channel, see gameMachine.
+// Moves are calculated and the game is fetched after a desync in the background, so that the events keep flowing
+// during long searches and while the server can't be reached.
+// If ponder is set, it runs on the opponent's turns and is cancelled as soon as the opponent moved.
+func PlayThrough(
 	ctx context.Context,
-	sseClient *sse.Client,
+	events EventSource,
 	gameService *connectfour.GameService,
 	chatService *chat.ChatService,
 	calculateNextMove engine.CalculateNextMove,
+	ponder engine.Ponder,
 	botId string,
 	game *connectfour.Game,
 ) error {
@@ -34,18 +60,77 @@ func playThrough(
 
 	sseCtx, sseCancel := context.WithCancel(ctx)
 	defer sseCancel()
-	resCh, err := sseClient.Connect(sseCtx, "connect-four-"+game.GameId)
+	resCh, err := events.Connect(sseCtx, "connect-four-"+game.GameId)
 	if err != nil {
 		return err
 	}
 
-	if game.CurrentPlayerId == botId {
-		if err := makeMove(botId, game, sseCtx, gameService, calculateNextMove); err != nil {
-			return err
-		}
-	}
+	moves := make(chan moveMade)
+	resyncs := make(chan resynced)
+	cancelMove, cancelPonder, cancelResync := func() {}, func() {}, func() {}
+	defer func() {
+		cancelMove()
+		cancelPonder()
+		cancelResync()
+	}()
 
+	m, fx := newGameMachine(botId, game)
 	for {
+		if m.state != stateMyTurn {
+			cancelMove()
+		}
+		if m.state != stateTheirTurn {
+			cancelPonder()
+		}
+		if m.state != stateDesynced {
+			cancelResync()
+		}
+
+		if fx.err != nil {
+			return fx.err
+		}
+		if fx.message != "" {
+			go chatService.WriteMessage(ctx, m.game.ChatId, botId, fx.message, fx.messageKind)
+		}
+		if fx.calculateMove {
+			cancelMove()
+			moveCtx, cancel := context.WithCancel(sseCtx)
+			cancelMove = cancel
+			go func(turn int, game *connectfour.Game) {
+				err := makeMove(botId, game, moveCtx, gameService, calculateNextMove)
+				select {
+				case moves <- moveMade{turn: turn, err: err}:
+				case <-moveCtx.Done():
+				}
+			}(m.turn, m.game.Clone())
+		}
+		if fx.ponder && ponder != nil {
+			cancelPonder()
+			ponderCtx, cancel := context.WithCancel(sseCtx)
+			cancelPonder = cancel
+			go ponder(ponderCtx, m.game.Clone())
+		}
+		if fx.resync {
+			cancelResync()
+			resyncCtx, cancel := context.WithCancel(sseCtx)
+			cancelResync = cancel
+			go func(gameId string, delay time.Duration) {
+				select {
+				case <-time.After(delay):
+				case <-resyncCtx.Done():
+					return
+				}
+				game, found, err := fetchRunningGame(resyncCtx, gameService, botId, gameId)
+				select {
+				case resyncs <- resynced{game: game, found: found, err: err}:
+				case <-resyncCtx.Done():
+				}
+			}(m.game.GameId, fx.resyncDelay)
+		}
+		if m.state == stateFinished {
+			return nil
+		}
+
 		select {
 		case <-sseCtx.Done():
 			return nil
@@ -53,64 +138,11 @@ func playThrough(
 			if res.Error != nil {
 				return res.Error
 			}
-
-			switch e := res.Event.(type) {
-			case sse.ChatAssigned:
-				game.ChatId = e.ChatId
-
-				go chatService.WriteMessage(
-					ctx,
-					game.ChatId,
-					botId,
-					"Good luck, have fun!",
-					"opening",
-				)
-			case sse.PlayerJoined:
-				if e.RedPlayerId != botId {
-					continue
-				}
-
-				if err := makeMove(botId, game, sseCtx, gameService, calculateNextMove); err != nil {
-					return err
-				}
-			case sse.PlayerMoved:
-				if ok := game.ApplyMove(e.X, e.Y); !ok {
-					continue
-				}
-
-				if e.NextPlayerId != botId {
-					continue
-				}
-
-				if err := makeMove(botId, game, sseCtx, gameService, calculateNextMove); err != nil {
-					return err
-				}
-			case sse.GameAborted:
-				if game.ChatId != "" {
-					go chatService.WriteMessage(
-						ctx,
-						game.ChatId,
-						botId,
-						"Next time, perhaps!",
-						"ending",
-					)
-				}
-				sseCancel()
-			case sse.GameWon,
-				sse.GameDrawn,
-				sse.GameTimedOut,
-				sse.GameResigned:
-				if game.ChatId != "" {
-					go chatService.WriteMessage(
-						ctx,
-						game.ChatId,
-						botId,
-						"Good game! Well played.",
-						"ending",
-					)
-				}
-				sseCancel()
-			}
+			fx = m.handle(res.Event)
+		case move := <-moves:
+			fx = m.handle(move)
+		case r := <-resyncs:
+			fx = m.handle(r)
 		}
 	}
 }
@@ -122,13 +154,128 @@ func makeMove(
 	gameService *connectfour.GameService,
 	calculateNextMove engine.CalculateNextMove,
 ) error {
-	c, ok := calculateNextMove(game)
+	if game.Outcome() != connectfour.Ongoing {
+		return nil // The game is finished, the server will announce it shortly.
+	}
+
+	c, ok := calculateNextMove(sseCtx, game)
 	if !ok {
-		return nil // The engine couldn't find a valid move. The game is probably already finished.
+		return nil // The engine couldn't find a valid move or gave up. The game or the turn is probably over.
 	}
 
 	// Ignoring errResp, probably the game is already finished if that's returned.
 	_, err := gameService.MakeMove(sseCtx, game.GameId, botId, int32(c))
+	if !isLostReply(sseCtx, err) {
+		return err
+	}
+
+	// The move may or may not have been made. Only try again if the server is still waiting for it.
+	serverGame, found, err := fetchRunningGame(sseCtx, gameService, botId, game.GameId)
+	if err != nil {
+		return err
+	} else if !found || serverGame.MoveCount() != game.MoveCount() || serverGame.CurrentPlayerId != botId {
+		return nil
+	}
+
+	_, err = gameService.MakeMove(sseCtx, game.GameId, botId, int32(c))
 
 	return err
 }
+
+// isLostReply reports whether a request timed out or lost its connection, while the caller is still interested.
+func isLostReply(ctx context.Context, err error) bool {
+	return ctx.Err() == nil &&
+		(errors.Is(err, rpcclient.ErrConnectionLost) || errors.Is(err, context.DeadlineExceeded))
+}
+
+// verifyOutcome records when the server ends the game differently than the local state suggests.
+func verifyOutcome(game *connectfour.Game, event any) {
+	var name string
+	var expected []connectfour.Outcome
+
+	switch event.(type) {
+	case sse.GameWon:
+		name, expected = "GameWon", []connectfour.Outcome{connectfour.RedWon, connectfour.YellowWon}
+	case sse.GameDrawn:
+		name, expected = "GameDrawn", []connectfour.Outcome{connectfour.Draw}
+	case sse.GameAborted:
+		name, expected = "GameAborted", []connectfour.Outcome{connectfour.Ongoing}
+	case sse.GameTimedOut:
+		name, expected = "GameTimedOut", []connectfour.Outcome{connectfour.Ongoing}
+	case sse.GameResigned:
+		name, expected = "GameResigned", []connectfour.Outcome{connectfour.Ongoing}
+	default:
+		return
+	}
+
+	if slices.Contains(expected, game.Outcome()) {
+		return
+	}
+
+	outcomeMismatchesCounter.With(map[string]string{"event": name, "outcome": game.Outcome().String()}).Inc()
+}
+
+// fetchRunningGame loads the game from the server, e.g. to recover from a desync.
+// It returns false if the game isn't running anymore.
+func fetchRunningGame(
+	ctx context.Context,
+	gameService *connectfour.GameService,
+	botId string,
+	gameId string,
+) (*connectfour.Game, bool, error) {
+	for page := int32(1); ; page++ {
+		runningGames, err := gameService.GetGamesByPlayer(
+			ctx,
+			botId,
+			connectfourv1.GetGamesByPlayer_STATE_RUNNING,
+			page,
+			100,
+		)
+		if err != nil {
+			return nil, false, err
+		} else if len(runningGames.Games) == 0 {
+			return nil, false, nil
+		}
+
+		for _, game := range runningGames.Games {
+			if game.GameId == gameId {
+				return gameFromProto(game), true, nil
+			}
+		}
+	}
+}
+
+func gameFromProto(game *connectfourv1.Game) *connectfour.Game {
+	gameModel := connectfour.NewGame(
+		game.GameId,
+		game.ChatId,
+		game.CurrentPlayerId,
+		int(game.Width),
+		int(game.Height),
+	)
+
+	for _, move := range game.Moves {
+		gameModel.ForceMove(int(move.X), int(move.Y), int(move.Color))
+	}
+
+	return gameModel
+}
+
+func desyncReason(err error) string {
+	switch {
+	case errors.Is(err, connectfour.ErrOutOfBounds):
+		return "out_of_bounds"
+	case errors.Is(err, connectfour.ErrFloatingStone):
+		return "floating_stone"
+	case errors.Is(err, connectfour.ErrColumnFull):
+		return "column_full"
+	case errors.Is(err, connectfour.ErrWrongColor):
+		return "wrong_color"
+	case errors.Is(err, connectfour.ErrCellOccupied):
+		return "cell_occupied"
+	case errors.Is(err, connectfour.ErrGameFinished):
+		return "game_finished"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,439 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gaming-platform/connect-four-bot/internal/chat"
+	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
+	"github.com/gaming-platform/connect-four-bot/internal/engine"
+	"github.com/gaming-platform/connect-four-bot/internal/platformtest"
+	"github.com/gaming-platform/connect-four-bot/internal/sse"
+)
+
+const (
+	botId      = "bot-1"
+	opponentId = "opponent"
+)
+
+// clientOptions reconnect fast, but not before the tests got to publish while the connection is down.
+var clientOptions = sse.ClientOptions{MaxReconnects: 5, ReconnectInterval: 50 * time.Millisecond}
+
+type testEnv struct {
+	platform    *platformtest.Platform
+	sseClient   *sse.Multiplexer
+	chatService *chat.ChatService
+	gameService *connectfour.GameService
+	ctx         context.Context
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	p := platformtest.New()
+	t.Cleanup(p.Close)
+	sseClient := sse.NewMultiplexer(sse.NewClient(p.NchanSubUrl(), clientOptions), sse.MultiplexerOptions{Debounce: time.Millisecond})
+	t.Cleanup(sseClient.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	return &testEnv{
+		platform:    p,
+		sseClient:   sseClient,
+		chatService: chat.NewChatService(p.RpcClient()),
+		gameService: connectfour.NewGameService(p.RpcClient()),
+		ctx:         ctx,
+	}
+}
+
+// play starts the bot and returns a channel with the result of Play.
+func (e *testEnv) play(bot Bot, ctx context.Context) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- bot.Play(ctx) }()
+
+	return done
+}
+
+// playOpponent makes the opponent play the column whenever it's their turn, until the game is over.
+func (e *testEnv) playOpponent(t *testing.T, gameId string, column int) {
+	t.Helper()
+
+	for {
+		game, err := e.platform.WaitForGame(e.ctx, gameId, func(g platformtest.Game) bool {
+			return g.CurrentPlayerId == opponentId || g.CurrentPlayerId == ""
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if game.CurrentPlayerId == "" {
+			return
+		}
+
+		e.opponentMoves(t, gameId, column)
+	}
+}
+
+func (e *testEnv) opponentMoves(t *testing.T, gameId string, column int) {
+	t.Helper()
+
+	if errResp, err := e.gameService.MakeMove(e.ctx, gameId, opponentId, int32(column)); err != nil || errResp != nil {
+		t.Fatalf("opponent MakeMove = %v, %v", errResp, err)
+	}
+}
+
+func (e *testEnv) waitForSubscription(t *testing.T, channel string) {
+	t.Helper()
+
+	if err := e.platform.WaitForSubscribers(e.ctx, channel, 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (e *testEnv) waitForMessage(t *testing.T, gameId string, message string) {
+	t.Helper()
+
+	_, err := e.platform.WaitForGame(e.ctx, gameId, func(g platformtest.Game) bool {
+		return slices.Contains(e.platform.Messages(g.ChatId), platformtest.ChatMessage{AuthorId: botId, Message: message})
+	})
+	if err != nil {
+		t.Fatalf("message %q not written: %v", message, err)
+	}
+}
+
+// waitForGameOpenedBy returns the first game the player opened, skipping the given number of games.
+func (e *testEnv) waitForGameOpenedBy(t *testing.T, playerId string, skip int) string {
+	t.Helper()
+
+	for {
+		var opened []string
+		for _, g := range e.platform.Games() {
+			if g.OpenerId == playerId {
+				opened = append(opened, g.GameId)
+			}
+		}
+		if len(opened) > skip {
+			return opened[skip]
+		}
+
+		select {
+		case <-time.After(time.Millisecond):
+		case <-e.ctx.Done():
+			t.Fatalf("player %s didn't open a game", playerId)
+		}
+	}
+}
+
+// preferColumn plays the column while it's available, otherwise the first available column.
+func preferColumn(column int) engine.CalculateNextMove {
+	return func(ctx context.Context, game *connectfour.Game) (int, bool) {
+		available := game.GetAvailableColumns()
+		if len(available) == 0 {
+			return 0, false
+		} else if slices.Contains(available, column) {
+			return column, true
+		}
+
+		return available[0], true
+	}
+}
+
+func TestOpeningBotPlaysOpenedGame(t *testing.T) {
+	e := newTestEnv(t)
+	ctx, cancel := context.WithCancel(e.ctx)
+	defer cancel()
+
+	done := e.play(NewOpeningBot(botId, preferColumn(1), nil, e.sseClient, e.chatService, e.gameService), ctx)
+
+	gameId := e.waitForGameOpenedBy(t, botId, 0)
+	e.waitForSubscription(t, "connect-four-"+gameId)
+	if errResp, err := e.gameService.JoinGame(e.ctx, gameId, opponentId); err != nil || errResp != nil {
+		t.Fatalf("JoinGame = %v, %v", errResp, err)
+	}
+	e.playOpponent(t, gameId, 2)
+
+	game, _ := e.platform.Game(gameId)
+	if game.State != platformtest.GameWon || game.WinnerId != botId {
+		t.Fatalf("game %s %s by %q; want won by the bot\n%s", gameId, game.State, game.WinnerId, connectfour.Render(game.Board))
+	}
+	e.waitForMessage(t, gameId, "Good luck, have fun!")
+	e.waitForMessage(t, gameId, "Good game! Well played.")
+
+	e.waitForGameOpenedBy(t, botId, 1) // The bot moves on to the next game.
+	cancel()
+	<-done
+}
+
+func TestOpeningBotOpensNextGameAfterAbort(t *testing.T) {
+	e := newTestEnv(t)
+	ctx, cancel := context.WithCancel(e.ctx)
+	defer cancel()
+
+	done := e.play(NewOpeningBot(botId, preferColumn(1), nil, e.sseClient, e.chatService, e.gameService), ctx)
+
+	gameId := e.waitForGameOpenedBy(t, botId, 0)
+	e.waitForSubscription(t, "connect-four-"+gameId)
+	if err := e.platform.Abort(gameId); err != nil {
+		t.Fatal(err)
+	}
+
+	nextGameId := e.waitForGameOpenedBy(t, botId, 1)
+	if game, _ := e.platform.Game(nextGameId); game.State != platformtest.GameOpen {
+		t.Fatalf("next game is %s; want open", game.State)
+	}
+	cancel()
+	<-done
+}
+
+func TestJoiningBotJoinsAndPlaysOpenGames(t *testing.T) {
+	e := newTestEnv(t)
+	ctx, cancel := context.WithCancel(e.ctx)
+	defer cancel()
+
+	gameId, err := e.gameService.OpenGame(e.ctx, opponentId, 7, 6, 1, "move:15000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot, err := NewJoiningBot(e.ctx, botId, preferColumn(2), nil, time.Millisecond, e.sseClient, e.chatService, e.gameService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := e.play(bot, ctx)
+
+	// Games opened while the bot is running are announced in the lobby.
+	e.waitForSubscription(t, "lobby")
+	laterGameId, err := e.gameService.OpenGame(e.ctx, opponentId, 7, 6, 1, "move:15000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{gameId, laterGameId} {
+		game, err := e.platform.WaitForGame(e.ctx, id, func(g platformtest.Game) bool { return g.YellowPlayerId != "" })
+		if err != nil {
+			t.Fatal(err)
+		} else if game.YellowPlayerId != botId {
+			t.Fatalf("game %s joined by %q; want the bot", id, game.YellowPlayerId)
+		}
+
+		e.waitForSubscription(t, "connect-four-"+id)
+		e.playOpponent(t, id, 1)
+
+		if game, _ := e.platform.Game(id); game.State != platformtest.GameWon || game.WinnerId != opponentId {
+			t.Fatalf("game %s %s by %q; want won by the opponent", id, game.State, game.WinnerId)
+		}
+	}
+
+	cancel()
+	<-done
+}
+
+func TestJoiningBotFindsGamesOpenedWhileDisconnected(t *testing.T) {
+	e := newTestEnv(t)
+	ctx, cancel := context.WithCancel(e.ctx)
+	defer cancel()
+
+	bot, err := NewJoiningBot(e.ctx, botId, preferColumn(2), nil, time.Millisecond, e.sseClient, e.chatService, e.gameService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := e.play(bot, ctx)
+
+	// The lobby stream hasn't received an event yet, so it reports a gap after reconnecting.
+	e.waitForSubscription(t, "lobby")
+	e.platform.DropSubscriptions()
+	gameId, err := e.gameService.OpenGame(e.ctx, opponentId, 7, 6, 1, "move:15000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	game, err := e.platform.WaitForGame(e.ctx, gameId, func(g platformtest.Game) bool { return g.YellowPlayerId != "" })
+	if err != nil {
+		t.Fatal(err)
+	} else if game.YellowPlayerId != botId {
+		t.Fatalf("game %s joined by %q; want the bot", gameId, game.YellowPlayerId)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestResumingBotContinuesRunningGames(t *testing.T) {
+	e := newTestEnv(t)
+
+	gameId := startGame(t, e, "121") // The bot plays yellow and is to move.
+	bot, err := NewResumingBot(e.ctx, botId, preferColumn(2), nil, e.sseClient, e.chatService, e.gameService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := e.play(bot, e.ctx)
+
+	e.waitForSubscription(t, "connect-four-"+gameId)
+	e.playOpponent(t, gameId, 1)
+
+	game, _ := e.platform.Game(gameId)
+	if game.State != platformtest.GameWon || connectfour.FormatMoves(game.Board) != "1212121" {
+		t.Fatalf("game %s %s after %q; want won after 1212121", gameId, game.State, connectfour.FormatMoves(game.Board))
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Play = %v; want nil after all games ended", err)
+	}
+}
+
+func TestResumingBotStopsAfterTimeout(t *testing.T) {
+	e := newTestEnv(t)
+
+	gameId := startGame(t, e, "12") // The opponent is to move and never does.
+	bot, err := NewResumingBot(e.ctx, botId, preferColumn(2), nil, e.sseClient, e.chatService, e.gameService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := e.play(bot, e.ctx)
+
+	e.waitForSubscription(t, "connect-four-"+gameId)
+	if err := e.platform.TimeOut(gameId); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("Play = %v; want nil after the timeout", err)
+	}
+	if game, _ := e.platform.Game(gameId); game.State != platformtest.GameTimedOut {
+		t.Fatalf("game %s is %s; want timed out", gameId, game.State)
+	}
+}
+
+func TestResumingBotCatchesUpAfterLostConnection(t *testing.T) {
+	e := newTestEnv(t)
+
+	gameId := startGame(t, e, "12")
+	bot, err := NewResumingBot(e.ctx, botId, preferColumn(2), nil, e.sseClient, e.chatService, e.gameService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := e.play(bot, e.ctx)
+
+	e.waitForSubscription(t, "connect-four-"+gameId)
+	e.opponentMoves(t, gameId, 1)
+	if _, err := e.platform.WaitForGame(e.ctx, gameId, func(g platformtest.Game) bool { return g.CurrentPlayerId == opponentId }); err != nil {
+		t.Fatal(err)
+	}
+
+	// The stream received events, so it resumes and Nchan resends the move.
+	e.platform.DropSubscriptions()
+	e.opponentMoves(t, gameId, 1)
+	e.playOpponent(t, gameId, 1)
+
+	game, _ := e.platform.Game(gameId)
+	if game.State != platformtest.GameWon || connectfour.FormatMoves(game.Board) != "1212121" {
+		t.Fatalf("game %s %s after %q; want won after 1212121", gameId, game.State, connectfour.FormatMoves(game.Board))
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Play = %v; want nil after all games ended", err)
+	}
+}
+
+func TestResumingBotResyncsAfterGap(t *testing.T) {
+	e := newTestEnv(t)
+
+	gameId := startGame(t, e, "12")
+	bot, err := NewResumingBot(e.ctx, botId, preferColumn(2), nil, e.sseClient, e.chatService, e.gameService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := e.play(bot, e.ctx)
+
+	// The stream hasn't received an event yet, so it can't resume and reports a gap instead.
+	e.waitForSubscription(t, "connect-four-"+gameId)
+	e.platform.DropSubscriptions()
+	e.opponentMoves(t, gameId, 1)
+	e.playOpponent(t, gameId, 1)
+
+	game, _ := e.platform.Game(gameId)
+	if game.State != platformtest.GameWon || connectfour.FormatMoves(game.Board) != "1212121" {
+		t.Fatalf("game %s %s after %q; want won after 1212121", gameId, game.State, connectfour.FormatMoves(game.Board))
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Play = %v; want nil after all games ended", err)
+	}
+}
+
+// injectedEvents is an event source the test feeds directly.
+type injectedEvents chan sse.ConnectChannelResult
+
+func (i injectedEvents) Connect(context.Context, string) (chan sse.ConnectChannelResult, error) {
+	return i, nil
+}
+
+func TestPlayThroughResyncsOnGap(t *testing.T) {
+	e := newTestEnv(t)
+
+	gameId := startGame(t, e, "12")
+	game, _, err := fetchRunningGame(e.ctx, e.gameService, botId, gameId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(injectedEvents)
+	done := make(chan error, 1)
+	go func() {
+		done <- PlayThrough(e.ctx, events, e.gameService, e.chatService, preferColumn(2), nil, botId, game)
+	}()
+
+	e.opponentMoves(t, gameId, 1) // Not announced to the bot.
+	events <- sse.ConnectChannelResult{Event: sse.Gap{Channel: "connect-four-" + gameId, Cause: sse.ErrNotResumable}}
+	if _, err := e.platform.WaitForGame(e.ctx, gameId, func(g platformtest.Game) bool { return g.CurrentPlayerId == opponentId }); err != nil {
+		t.Fatal(err)
+	}
+	if game, _ := e.platform.Game(gameId); connectfour.FormatMoves(game.Board) != "1212" {
+		t.Fatalf("moves %q after the gap; want 1212", connectfour.FormatMoves(game.Board))
+	}
+
+	events <- sse.ConnectChannelResult{Event: sse.GameAborted{GameId: gameId}}
+	if err := <-done; err != nil {
+		t.Fatalf("PlayThrough = %v; want nil after the game ended", err)
+	}
+}
+
+func TestPlayThroughReturnsErrorOfEventSource(t *testing.T) {
+	e := newTestEnv(t)
+
+	gameId := startGame(t, e, "12")
+	game, _, err := fetchRunningGame(e.ctx, e.gameService, botId, gameId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(injectedEvents, 1)
+	events <- sse.ConnectChannelResult{Error: errors.New("connection lost")}
+
+	if err := PlayThrough(e.ctx, events, e.gameService, e.chatService, preferColumn(2), nil, botId, game); err == nil {
+		t.Fatal("PlayThrough = nil; want the error of the event source")
+	}
+}
+
+// startGame lets the opponent open a game as red, the bot join as yellow, and both play the given move sequence.
+func startGame(t *testing.T, e *testEnv, moves string) string {
+	t.Helper()
+
+	played, err := connectfour.ParseMoves(7, 6, moves)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gameId, err := e.gameService.OpenGame(e.ctx, opponentId, 7, 6, 1, "move:15000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errResp, err := e.gameService.JoinGame(e.ctx, gameId, botId); err != nil || errResp != nil {
+		t.Fatalf("JoinGame = %v, %v", errResp, err)
+	}
+
+	players := []string{opponentId, botId}
+	for i, mv := range played.Moves() {
+		if errResp, err := e.gameService.MakeMove(e.ctx, gameId, players[i%2], int32(mv.X)); err != nil || errResp != nil {
+			t.Fatalf("MakeMove = %v, %v", errResp, err)
+		}
+	}
+
+	return gameId
+}
@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
+	"github.com/gaming-platform/connect-four-bot/internal/sse"
+)
+
+const (
+	resyncMinBackoff = 100 * time.Millisecond
+	resyncMaxBackoff = 5 * time.Second
+)
+
+// gameState is the stage of a game from the bot's point of view, see gameMachine.
+type gameState int
+
+const (
+	// stateOpen means that nobody joined yet, so the order of the players is unknown.
+	stateOpen gameState = iota
+	// stateWaitingForOpponent means that the bot's move is made, but the server hasn't confirmed it yet.
+	stateWaitingForOpponent
+	// stateMyTurn means that the bot calculates and makes its move.
+	stateMyTurn
+	// stateTheirTurn means that the server announced that the opponent is to move.
+	stateTheirTurn
+	// stateFinished means that the game ended or isn't running anymore. It's final.
+	stateFinished
+	// stateDesynced means that the local game can't follow the server's anymore and is fetched again.
+	stateDesynced
+)
+
+func (s gameState) String() string {
+	switch s {
+	case stateOpen:
+		return "open"
+	case stateWaitingForOpponent:
+		return "waiting_for_opponent"
+	case stateMyTurn:
+		return "my_turn"
+	case stateTheirTurn:
+		return "their_turn"
+	case stateFinished:
+		return "finished"
+	case stateDesynced:
+		return "desynced"
+	default:
+		return "unknown"
+	}
+}
+
+// moveMade is the result of the move calculation started for the turn, see effects.calculateMove.
+type moveMade struct {
+	turn int
+	err  error
+}
+
+// resynced is the result of fetching the game, see effects.resync.
+type resynced struct {
+	game  *connectfour.Game // nil if the game isn't running anymore.
+	found bool
+	err   error // The game couldn't be fetched, it's tried again.
+}
+
+// effects are the actions a transition asks PlayThrough for.
+type effects struct {
+	calculateMove bool          // Calculate and make the move for the current turn in the background.
+	ponder        bool          // Search ahead in the background until the opponent moved.
+	resync        bool          // Fetch the game and hand it in as resynced.
+	resyncDelay   time.Duration // Wait before fetching, after failed attempts.
+	message       string        // Write the message to the chat of the game.
+	messageKind   string
+	err           error // The game can't be played on.
+}
+
+// gameMachine follows a game through the events of its channel. It doesn't do any I/O itself,
+// the effects of each transition are carried out by PlayThrough.
+type gameMachine struct {
+	botId string
+	game  *connectfour.Game
+	state gameState
+	turn  int // Increased on each calculateMove, so that results of earlier turns can be told apart.
+	// resyncFailures counts the failed attempts to fetch the game since the last desync.
+	resyncFailures int
+}
+
+func newGameMachine(botId string, game *connectfour.Game) (*gameMachine, effects) {
+	m := &gameMachine{botId: botId, game: game}
+
+	return m, m.follow(game.CurrentPlayerId)
+}
+
+// handle transitions on the event, which is an sse event, moveMade or resynced.
+func (m *gameMachine) handle(event any) effects {
+	if m.state == stateFinished {
+		return effects{}
+	}
+
+	switch e := event.(type) {
+	case sse.PlayerJoined:
+		if m.state != stateOpen {
+			return effects{}
+		}
+		return m.follow(e.RedPlayerId)
+	case sse.ChatAssigned:
+		m.game.ChatId = e.ChatId
+		return effects{message: "Good luck, have fun!", messageKind: "opening"}
+	case sse.PlayerMoved:
+		if m.state == stateDesynced {
+			return effects{}
+		}
+		return m.playerMoved(e)
+	case sse.Gap:
+		// Moves may have been missed while the stream was down.
+		return m.desync()
+	case moveMade:
+		if m.state != stateMyTurn || e.turn != m.turn {
+			return effects{} // The turn is over already.
+		} else if e.err != nil {
+			return effects{err: e.err}
+		}
+		m.state = stateWaitingForOpponent
+		return effects{}
+	case resynced:
+		if m.state != stateDesynced {
+			return effects{}
+		} else if e.err != nil {
+			// Giving up would end all games of the bot, the game's end is announced as event anyway.
+			m.resyncFailures++
+			return effects{resync: true, resyncDelay: resyncBackoff(m.resyncFailures)}
+		} else if !e.found {
+			m.state = stateFinished // The game has ended in the meantime.
+			return effects{}
+		}
+		if e.game.ChatId == "" {
+			e.game.ChatId = m.game.ChatId
+		}
+		m.game = e.game
+		return m.follow(e.game.CurrentPlayerId)
+	case sse.GameAborted:
+		return m.finish(event, "Next time, perhaps!")
+	case sse.GameWon, sse.GameDrawn, sse.GameTimedOut, sse.GameResigned:
+		return m.finish(event, "Good game! Well played.")
+	default:
+		return effects{}
+	}
+}
+
+func (m *gameMachine) playerMoved(e sse.PlayerMoved) effects {
+	ok, err := m.game.PlayMove(e.X, e.Y, e.Color)
+	if err != nil {
+		desyncsCounter.With(map[string]string{"reason": desyncReason(err)}).Inc()
+		return m.desync()
+	} else if !ok {
+		return effects{} // Already known, e.g. after a resync.
+	}
+
+	return m.follow(e.NextPlayerId)
+}
+
+// follow moves on to the turn of the given player, an empty id means that nobody joined yet.
+func (m *gameMachine) follow(currentPlayerId string) effects {
+	m.game.CurrentPlayerId = currentPlayerId
+
+	switch currentPlayerId {
+	case "":
+		m.state = stateOpen
+		return effects{}
+	case m.botId:
+		m.state = stateMyTurn
+		m.turn++
+		return effects{calculateMove: true}
+	default:
+		m.state = stateTheirTurn
+		return effects{ponder: true}
+	}
+}
+
+func (m *gameMachine) desync() effects {
+	m.state = stateDesynced
+	m.resyncFailures = 0
+
+	return effects{resync: true}
+}
+
+// resyncBackoff grows exponentially with the number of failed attempts.
+func resyncBackoff(failures int) time.Duration {
+	d := resyncMinBackoff << (failures - 1)
+	if d <= 0 || d > resyncMaxBackoff {
+		return resyncMaxBackoff
+	}
+
+	return d
+}
+
+func (m *gameMachine) finish(event any, message string) effects {
+	verifyOutcome(m.game, event)
+	m.state = stateFinished
+	if m.game.ChatId == "" {
+		return effects{}
+	}
+
+	return effects{message: message, messageKind: "ending"}
+}
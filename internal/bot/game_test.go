@@ -0,0 +1,375 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
+	"github.com/gaming-platform/connect-four-bot/internal/sse"
+)
+
+// machineIn returns a machine of a game where the bot is red, after the moves in the column notation.
+func machineIn(t *testing.T, state gameState, moves string, chatId string) *gameMachine {
+	t.Helper()
+
+	game, err := connectfour.ParseMoves(7, 6, moves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.GameId, game.ChatId = "g", chatId
+
+	return &gameMachine{botId: botId, game: game, state: state, turn: 1}
+}
+
+func TestGameMachineStartsInStateOfCurrentPlayer(t *testing.T) {
+	tests := []struct {
+		currentPlayerId string
+		want            gameState
+		wantEffects     effects
+	}{
+		{"", stateOpen, effects{}},
+		{botId, stateMyTurn, effects{calculateMove: true}},
+		{opponentId, stateTheirTurn, effects{ponder: true}},
+	}
+	for _, tt := range tests {
+		m, fx := newGameMachine(botId, connectfour.NewGame("g", "", tt.currentPlayerId, 7, 6))
+		if m.state != tt.want || fx != tt.wantEffects {
+			t.Errorf("start with %q = %v, %+v; want %v, %+v", tt.currentPlayerId, m.state, fx, tt.want, tt.wantEffects)
+		}
+	}
+}
+
+func TestGameMachineTransitions(t *testing.T) {
+	errMove := errors.New("move failed")
+	serverGame := func(currentPlayerId string) *connectfour.Game {
+		game, _ := connectfour.ParseMoves(7, 6, "12")
+		game.GameId, game.CurrentPlayerId = "g", currentPlayerId
+		return game
+	}
+
+	tests := []struct {
+		name        string
+		machine     *gameMachine
+		event       any
+		want        gameState
+		wantEffects effects
+	}{
+		{
+			name:        "open, joined as red",
+			machine:     machineIn(t, stateOpen, "", ""),
+			event:       sse.PlayerJoined{GameId: "g", RedPlayerId: botId, YellowPlayerId: opponentId},
+			want:        stateMyTurn,
+			wantEffects: effects{calculateMove: true},
+		},
+		{
+			name:        "open, joined as yellow",
+			machine:     machineIn(t, stateOpen, "", ""),
+			event:       sse.PlayerJoined{GameId: "g", RedPlayerId: opponentId, YellowPlayerId: botId},
+			want:        stateTheirTurn,
+			wantEffects: effects{ponder: true},
+		},
+		{
+			name:        "open, chat assigned",
+			machine:     machineIn(t, stateOpen, "", ""),
+			event:       sse.ChatAssigned{GameId: "g", ChatId: "c"},
+			want:        stateOpen,
+			wantEffects: effects{message: "Good luck, have fun!", messageKind: "opening"},
+		},
+		{
+			name:    "open, aborted without chat",
+			machine: machineIn(t, stateOpen, "", ""),
+			event:   sse.GameAborted{GameId: "g"},
+			want:    stateFinished,
+		},
+		{
+			name:        "my turn, aborted with chat",
+			machine:     machineIn(t, stateMyTurn, "", "c"),
+			event:       sse.GameAborted{GameId: "g"},
+			want:        stateFinished,
+			wantEffects: effects{message: "Next time, perhaps!", messageKind: "ending"},
+		},
+		{
+			name:    "my turn, joined again",
+			machine: machineIn(t, stateMyTurn, "", ""),
+			event:   sse.PlayerJoined{GameId: "g", RedPlayerId: botId},
+			want:    stateMyTurn,
+		},
+		{
+			name:    "my turn, move made",
+			machine: machineIn(t, stateMyTurn, "", ""),
+			event:   moveMade{turn: 1},
+			want:    stateWaitingForOpponent,
+		},
+		{
+			name:    "my turn, move of earlier turn made",
+			machine: machineIn(t, stateMyTurn, "", ""),
+			event:   moveMade{turn: 0, err: errMove},
+			want:    stateMyTurn,
+		},
+		{
+			name:        "my turn, move failed",
+			machine:     machineIn(t, stateMyTurn, "", ""),
+			event:       moveMade{turn: 1, err: errMove},
+			want:        stateMyTurn,
+			wantEffects: effects{err: errMove},
+		},
+		{
+			name:        "my turn, own move confirmed before it was reported made",
+			machine:     machineIn(t, stateMyTurn, "", ""),
+			event:       sse.PlayerMoved{GameId: "g", X: 1, Y: 6, Color: 1, PlayerId: botId, NextPlayerId: opponentId},
+			want:        stateTheirTurn,
+			wantEffects: effects{ponder: true},
+		},
+		{
+			name:        "my turn, gap",
+			machine:     machineIn(t, stateMyTurn, "", ""),
+			event:       sse.Gap{Channel: "connect-four-g", Cause: sse.ErrNotResumable},
+			want:        stateDesynced,
+			wantEffects: effects{resync: true},
+		},
+		{
+			name:        "waiting for opponent, own move confirmed",
+			machine:     machineIn(t, stateWaitingForOpponent, "", ""),
+			event:       sse.PlayerMoved{GameId: "g", X: 1, Y: 6, Color: 1, PlayerId: botId, NextPlayerId: opponentId},
+			want:        stateTheirTurn,
+			wantEffects: effects{ponder: true},
+		},
+		{
+			name:    "waiting for opponent, known move",
+			machine: machineIn(t, stateWaitingForOpponent, "1", ""),
+			event:   sse.PlayerMoved{GameId: "g", X: 1, Y: 6, Color: 1, PlayerId: botId, NextPlayerId: opponentId},
+			want:    stateWaitingForOpponent,
+		},
+		{
+			name:        "their turn, opponent moved",
+			machine:     machineIn(t, stateTheirTurn, "1", ""),
+			event:       sse.PlayerMoved{GameId: "g", X: 2, Y: 6, Color: 2, PlayerId: opponentId, NextPlayerId: botId},
+			want:        stateMyTurn,
+			wantEffects: effects{calculateMove: true},
+		},
+		{
+			name:        "their turn, move that doesn't fit",
+			machine:     machineIn(t, stateTheirTurn, "1", ""),
+			event:       sse.PlayerMoved{GameId: "g", X: 2, Y: 3, Color: 2, PlayerId: opponentId, NextPlayerId: botId},
+			want:        stateDesynced,
+			wantEffects: effects{resync: true},
+		},
+		{
+			name:    "their turn, move made late",
+			machine: machineIn(t, stateTheirTurn, "1", ""),
+			event:   moveMade{turn: 1},
+			want:    stateTheirTurn,
+		},
+		{
+			name:        "their turn, won with chat",
+			machine:     machineIn(t, stateTheirTurn, "1212121", "c"),
+			event:       sse.GameWon{GameId: "g", WinnerPlayerId: botId},
+			want:        stateFinished,
+			wantEffects: effects{message: "Good game! Well played.", messageKind: "ending"},
+		},
+		{
+			name:        "desynced, resynced on bot's turn",
+			machine:     machineIn(t, stateDesynced, "1", ""),
+			event:       resynced{game: serverGame(botId), found: true},
+			want:        stateMyTurn,
+			wantEffects: effects{calculateMove: true},
+		},
+		{
+			name:        "desynced, resynced on opponent's turn",
+			machine:     machineIn(t, stateDesynced, "1", ""),
+			event:       resynced{game: serverGame(opponentId), found: true},
+			want:        stateTheirTurn,
+			wantEffects: effects{ponder: true},
+		},
+		{
+			name:    "desynced, game not running anymore",
+			machine: machineIn(t, stateDesynced, "1", ""),
+			event:   resynced{},
+			want:    stateFinished,
+		},
+		{
+			name:        "desynced, resync failed",
+			machine:     machineIn(t, stateDesynced, "1", ""),
+			event:       resynced{err: errors.New("unreachable")},
+			want:        stateDesynced,
+			wantEffects: effects{resync: true, resyncDelay: resyncMinBackoff},
+		},
+		{
+			name:    "desynced, aborted",
+			machine: machineIn(t, stateDesynced, "1", ""),
+			event:   sse.GameAborted{GameId: "g"},
+			want:    stateFinished,
+		},
+		{
+			name:    "desynced, moved",
+			machine: machineIn(t, stateDesynced, "1", ""),
+			event:   sse.PlayerMoved{GameId: "g", X: 2, Y: 6, Color: 2, PlayerId: opponentId, NextPlayerId: botId},
+			want:    stateDesynced,
+		},
+		{
+			name:    "their turn, resynced late",
+			machine: machineIn(t, stateTheirTurn, "1", ""),
+			event:   resynced{game: serverGame(botId), found: true},
+			want:    stateTheirTurn,
+		},
+		{
+			name:    "finished, moved",
+			machine: machineIn(t, stateFinished, "1", ""),
+			event:   sse.PlayerMoved{GameId: "g", X: 2, Y: 6, Color: 2, PlayerId: opponentId, NextPlayerId: botId},
+			want:    stateFinished,
+		},
+		{
+			name:    "finished, gap",
+			machine: machineIn(t, stateFinished, "1", ""),
+			event:   sse.Gap{Channel: "connect-four-g", Cause: sse.ErrNotResumable},
+			want:    stateFinished,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fx := tt.machine.handle(tt.event)
+			if tt.machine.state != tt.want || fx != tt.wantEffects {
+				t.Errorf("handle = %v, %+v; want %v, %+v", tt.machine.state, fx, tt.want, tt.wantEffects)
+			}
+		})
+	}
+}
+
+func TestGameMachineBacksOffWhileResyncFails(t *testing.T) {
+	m := machineIn(t, stateTheirTurn, "1", "")
+	m.handle(sse.Gap{Channel: "connect-four-g", Cause: sse.ErrNotResumable})
+
+	var delays []time.Duration
+	for range 8 {
+		delays = append(delays, m.handle(resynced{err: errors.New("unreachable")}).resyncDelay)
+	}
+	want := []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond,
+		1600 * time.Millisecond, 3200 * time.Millisecond, 5 * time.Second, 5 * time.Second,
+	}
+	if !slices.Equal(delays, want) {
+		t.Fatalf("delays = %v; want %v", delays, want)
+	}
+
+	// A new desync starts over.
+	m.handle(resynced{game: connectfour.NewGame("g", "", opponentId, 7, 6), found: true})
+	m.handle(sse.Gap{Channel: "connect-four-g", Cause: sse.ErrNotResumable})
+	if fx := m.handle(resynced{err: errors.New("unreachable")}); fx.resyncDelay != resyncMinBackoff {
+		t.Fatalf("delay after a new desync = %v; want %v", fx.resyncDelay, resyncMinBackoff)
+	}
+}
+
+func TestGameMachineKeepsChatAfterResync(t *testing.T) {
+	m := machineIn(t, stateDesynced, "1", "c")
+	m.handle(resynced{game: connectfour.NewGame("g", "", opponentId, 7, 6), found: true})
+
+	if m.game.ChatId != "c" {
+		t.Fatalf("chat after resync = %q; want c", m.game.ChatId)
+	}
+}
+
+func TestGameMachineStartsNewTurnAfterEachCalculation(t *testing.T) {
+	m := machineIn(t, stateTheirTurn, "1", "")
+	m.handle(sse.PlayerMoved{GameId: "g", X: 2, Y: 6, Color: 2, PlayerId: opponentId, NextPlayerId: botId})
+
+	if fx := m.handle(moveMade{turn: 1, err: errors.New("canceled")}); fx != (effects{}) || m.state != stateMyTurn {
+		t.Fatalf("result of the previous turn = %v, %+v; want it ignored", m.state, fx)
+	}
+}
+
+func TestPlayThroughHandlesEventsDuringCalculation(t *testing.T) {
+	e := newTestEnv(t)
+
+	gameId := startGame(t, e, "1") // The bot is to move.
+	game, _, err := fetchRunningGame(e.ctx, e.gameService, botId, gameId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calculating, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	slowEngine := func(ctx context.Context, game *connectfour.Game) (int, bool) {
+		close(calculating)
+		<-release
+		return 1, true
+	}
+
+	events := make(injectedEvents)
+	done := make(chan error, 1)
+	go func() {
+		done <- PlayThrough(context.Background(), events, e.gameService, e.chatService, slowEngine, nil, botId, game)
+	}()
+
+	<-calculating
+	events <- sse.ConnectChannelResult{Event: sse.GameAborted{GameId: gameId}}
+	if err := <-done; err != nil {
+		t.Fatalf("PlayThrough = %v; want nil after the game ended", err)
+	}
+}
+
+func TestPlayThroughCancelsCalculationWhenGameEnds(t *testing.T) {
+	e := newTestEnv(t)
+
+	gameId := startGame(t, e, "1") // The bot is to move.
+	game, _, err := fetchRunningGame(e.ctx, e.gameService, botId, gameId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calculating, cancelled := make(chan struct{}), make(chan struct{})
+	searchingEngine := func(ctx context.Context, game *connectfour.Game) (int, bool) {
+		close(calculating)
+		<-ctx.Done()
+		close(cancelled)
+		return 0, false
+	}
+
+	events := make(injectedEvents)
+	go func() {
+		_ = PlayThrough(context.Background(), events, e.gameService, e.chatService, searchingEngine, nil, botId, game)
+	}()
+
+	<-calculating
+	events <- sse.ConnectChannelResult{Event: sse.GameResigned{GameId: gameId}}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("calculation not cancelled after the game ended")
+	}
+}
+
+func TestPlayThroughPondersUntilOpponentMoved(t *testing.T) {
+	e := newTestEnv(t)
+
+	gameId := startGame(t, e, "12") // The opponent is to move.
+	game, _, err := fetchRunningGame(e.ctx, e.gameService, botId, gameId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pondering, cancelled := make(chan struct{}), make(chan struct{})
+	ponder := func(ctx context.Context, game *connectfour.Game) {
+		close(pondering)
+		<-ctx.Done()
+		close(cancelled)
+	}
+
+	events := make(injectedEvents)
+	done := make(chan error, 1)
+	go func() {
+		done <- PlayThrough(context.Background(), events, e.gameService, e.chatService, preferColumn(2), ponder, botId, game)
+	}()
+
+	<-pondering
+	events <- sse.ConnectChannelResult{Event: sse.PlayerMoved{GameId: gameId, X: 3, Y: 6, Color: 1, PlayerId: opponentId, NextPlayerId: botId}}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pondering not cancelled after the opponent moved")
+	}
+
+	events <- sse.ConnectChannelResult{Event: sse.GameAborted{GameId: gameId}}
+	if err := <-done; err != nil {
+		t.Fatalf("PlayThrough = %v; want nil after the game ended", err)
+	}
+}
@@ -2,17 +2,26 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/gaming-platform/connect-four-bot/internal/api"
 	"github.com/gaming-platform/connect-four-bot/internal/chat"
 	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
 	"github.com/gaming-platform/connect-four-bot/internal/engine"
 	"github.com/gaming-platform/connect-four-bot/internal/sse"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"golang.org/x/sync/errgroup"
 )
 
+var joinRejectionsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "connect_four_bot_join_rejections_total",
+	Help: "The number of games the bot couldn't join, by the reason given by the server.",
+}, []string{"reason"})
+
 type openGame struct {
 	gameId string
 	width  int
@@ -23,9 +32,10 @@ type openGame struct {
 type JoiningBot struct {
 	botId             string
 	calculateNextMove engine.CalculateNextMove
+	ponder            engine.Ponder
 	games             sync.Map
 	joinAfter         time.Duration
-	sseClient         *sse.Client
+	events            EventSource
 	chatService       *chat.ChatService
 	gameService       *connectfour.GameService
 }
@@ -34,41 +44,51 @@ func NewJoiningBot(
 	ctx context.Context,
 	botId string,
 	calculateNextMove engine.CalculateNextMove,
+	ponder engine.Ponder,
 	joinAfter time.Duration,
-	client *sse.Client,
+	events EventSource,
 	chatSvc *chat.ChatService,
 	gameSvc *connectfour.GameService,
 ) (Bot, error) {
-	openGamesResp, err := gameSvc.GetOpenGames(ctx, 100)
+	b := &JoiningBot{
+		botId:             botId,
+		calculateNextMove: calculateNextMove,
+		ponder:            ponder,
+		joinAfter:         joinAfter,
+		events:            events,
+		chatService:       chatSvc,
+		gameService:       gameSvc,
+	}
+	if err := b.storeOpenGames(ctx); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// storeOpenGames adds the open games of other players that aren't known yet.
+func (b *JoiningBot) storeOpenGames(ctx context.Context) error {
+	openGamesResp, err := b.gameService.GetOpenGames(ctx, 100)
 	if err != nil {
-		return nil, fmt.Errorf("joining bot: could not fetch open games: %w", err)
+		return fmt.Errorf("joining bot: could not fetch open games: %w", err)
 	}
 
-	games := sync.Map{}
 	for _, game := range openGamesResp.Games {
-		if game.PlayerId == botId {
+		if game.PlayerId == b.botId {
 			continue
 		}
 
-		games.Store(
+		b.games.LoadOrStore(
 			game.GameId,
 			openGame{
-				joinAt: time.Now().Add(joinAfter),
+				joinAt: time.Now().Add(b.joinAfter),
 				width:  int(game.Width),
 				height: int(game.Height),
 			},
 		)
 	}
 
-	return &JoiningBot{
-		botId:             botId,
-		calculateNextMove: calculateNextMove,
-		joinAfter:         joinAfter,
-		games:             games,
-		sseClient:         client,
-		chatService:       chatSvc,
-		gameService:       gameSvc,
-	}, nil
+	return nil
 }
 
 func (b *JoiningBot) Play(ctx context.Context) error {
@@ -106,16 +126,18 @@ func (b *JoiningBot) joinGames(ctx context.Context, eg *errgroup.Group) error {
 					if err != nil {
 						return fmt.Errorf("joining bot: could not join game %s: %w", gameId, err)
 					} else if errorResp != nil {
+						joinRejectionsCounter.With(map[string]string{"reason": joinRejectionReason(errorResp)}).Inc()
 						return nil // Could not join, likely somebody else did in the meantime.
 					}
 
 					eg.Go(func() error {
-						return playThrough(
+						return PlayThrough(
 							ctx,
-							b.sseClient,
+							b.events,
 							b.gameService,
 							b.chatService,
 							b.calculateNextMove,
+							b.ponder,
 							b.botId,
 							connectfour.NewGame(gameId, "", "", game.width, game.height),
 						)
@@ -139,7 +161,7 @@ func (b *JoiningBot) joinGames(ctx context.Context, eg *errgroup.Group) error {
 func (b *JoiningBot) watchLobby(ctx context.Context) error {
 	sseCtx, sseCancel := context.WithCancel(ctx)
 	defer sseCancel()
-	resCh, err := b.sseClient.Connect(sseCtx, "lobby")
+	resCh, err := b.events.Connect(sseCtx, "lobby")
 	if err != nil {
 		return err
 	}
@@ -167,7 +189,24 @@ func (b *JoiningBot) watchLobby(ctx context.Context) error {
 				b.games.Delete(e.GameId)
 			case sse.PlayerJoined:
 				b.games.Delete(e.GameId)
+			case sse.Gap:
+				// Games may have been opened while the stream was down.
+				if err := b.storeOpenGames(sseCtx); err != nil {
+					return err
+				}
 			}
 		}
 	}
 }
+
+// joinRejectionReason tells races with other players, which are expected, apart from actual problems.
+func joinRejectionReason(errResp *api.ErrorResponse) string {
+	switch {
+	case errors.Is(errResp, api.ViolationPlayerAlreadyJoined), errors.Is(errResp, api.ViolationGameNotOpen):
+		return "already_joined"
+	case errors.Is(errResp, api.ViolationGameNotFound):
+		return "game_not_found"
+	default:
+		return "other"
+	}
+}
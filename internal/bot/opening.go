@@ -7,13 +7,13 @@ import (
 	"github.com/gaming-platform/connect-four-bot/internal/chat"
 	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
 	"github.com/gaming-platform/connect-four-bot/internal/engine"
-	"github.com/gaming-platform/connect-four-bot/internal/sse"
 )
 
 type OpeningBot struct {
 	botId             string
 	calculateNextMove engine.CalculateNextMove
-	sseClient         *sse.Client
+	ponder            engine.Ponder
+	events            EventSource
 	chatService       *chat.ChatService
 	gameService       *connectfour.GameService
 }
@@ -21,14 +21,16 @@ type OpeningBot struct {
 func NewOpeningBot(
 	botId string,
 	calculateNextMove engine.CalculateNextMove,
-	client *sse.Client,
+	ponder engine.Ponder,
+	events EventSource,
 	chatSvc *chat.ChatService,
 	gameSvc *connectfour.GameService,
 ) Bot {
 	return &OpeningBot{
 		botId:             botId,
 		calculateNextMove: calculateNextMove,
-		sseClient:         client,
+		ponder:            ponder,
+		events:            events,
 		chatService:       chatSvc,
 		gameService:       gameSvc,
 	}
@@ -43,11 +45,12 @@ func (b *OpeningBot) Play(ctx context.Context) error {
 			return err
 		}
 
-		if err := playThrough(
-			ctx, b.sseClient,
+		if err := PlayThrough(
+			ctx, b.events,
 			b.gameService,
 			b.chatService,
 			b.calculateNextMove,
+			b.ponder,
 			b.botId,
 			connectfour.NewGame(gameId, "", "", width, height),
 		); err != nil {
@@ -7,15 +7,15 @@ import (
 	"github.com/gaming-platform/connect-four-bot/internal/chat"
 	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
 	"github.com/gaming-platform/connect-four-bot/internal/engine"
-	"github.com/gaming-platform/connect-four-bot/internal/sse"
 	"golang.org/x/sync/errgroup"
 )
 
 type ResumingBot struct {
 	botId             string
 	calculateNextMove engine.CalculateNextMove
+	ponder            engine.Ponder
 	games             []*connectfourv1.Game
-	sseClient         *sse.Client
+	events            EventSource
 	chatService       *chat.ChatService
 	gameService       *connectfour.GameService
 }
@@ -24,7 +24,8 @@ func NewResumingBot(
 	ctx context.Context,
 	botId string,
 	calculateNextMove engine.CalculateNextMove,
-	client *sse.Client,
+	ponder engine.Ponder,
+	events EventSource,
 	chatSvc *chat.ChatService,
 	gameSvc *connectfour.GameService,
 ) (Bot, error) {
@@ -56,8 +57,9 @@ func NewResumingBot(
 	return &ResumingBot{
 		botId:             botId,
 		calculateNextMove: calculateNextMove,
+		ponder:            ponder,
 		games:             games,
-		sseClient:         client,
+		events:            events,
 		chatService:       chatSvc,
 		gameService:       gameSvc,
 	}, nil
@@ -68,26 +70,15 @@ func (b *ResumingBot) Play(ctx context.Context) error {
 
 	for _, game := range b.games {
 		eg.Go(func() error {
-			gameModel := connectfour.NewGame(
-				game.GameId,
-				game.ChatId,
-				game.CurrentPlayerId,
-				int(game.Width),
-				int(game.Height),
-			)
-
-			for _, move := range game.Moves {
-				gameModel.ForceMove(int(move.X), int(move.Y), int(move.Color))
-			}
-
-			return playThrough(
+			return PlayThrough(
 				ctx,
-				b.sseClient,
+				b.events,
 				b.gameService,
 				b.chatService,
 				b.calculateNextMove,
+				b.ponder,
 				b.botId,
-				gameModel,
+				gameFromProto(game),
 			)
 		})
 	}
@@ -2,13 +2,10 @@ package chat
 
 import (
 	"context"
-	"errors"
 
 	chatv1 "github.com/gaming-platform/api/go/chat/v1"
-	commonv1 "github.com/gaming-platform/api/go/common/v1"
 	"github.com/gaming-platform/connect-four-bot/internal/api"
 	"github.com/gaming-platform/connect-four-bot/internal/rpcclient"
-	"google.golang.org/protobuf/proto"
 )
 
 type ChatService struct {
@@ -28,23 +25,13 @@ func (s *ChatService) WriteMessage(
 	message string,
 	idempotencyKey string,
 ) (*api.ErrorResponse, error) {
-	req := chatv1.WriteMessage{IdempotencyKey: idempotencyKey, ChatId: chatId, AuthorId: authorId, Message: message}
-	reqBody, err := proto.Marshal(&req)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := s.rpcClient.Call(ctx, rpcclient.Message{Name: chatv1.WriteMessageType, Body: reqBody})
-	if err != nil {
-		return nil, err
-	}
+	_, err := api.Call[chatv1.WriteMessageResponse](
+		ctx,
+		s.rpcClient,
+		chatv1.WriteMessageType,
+		&chatv1.WriteMessage{IdempotencyKey: idempotencyKey, ChatId: chatId, AuthorId: authorId, Message: message},
+		chatv1.WriteMessageResponseType,
+	)
 
-	switch resp.Name {
-	case chatv1.WriteMessageResponseType:
-		return nil, nil
-	case commonv1.ErrorResponseType:
-		return api.NewErrorResponse(resp.Body)
-	default:
-		return nil, errors.New("unknown response")
-	}
+	return api.AsErrorResponse(err)
 }
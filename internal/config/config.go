@@ -11,10 +11,49 @@ type Config struct {
 	Username    string        `env:"APP_USERNAME,required"`
 	Level       int           `env:"APP_LEVEL,required"`
 	JoinAfter   time.Duration `env:"APP_JOIN_AFTER,required"`
-	RabbitMqDsn string        `env:"APP_RABBIT_MQ_DSN,required"`
+	RabbitMqDsn string        `env:"APP_RABBIT_MQ_DSN"`
 	NchanSubUrl string        `env:"APP_NCHAN_SUB_URL,required"`
 	RpcTimeout  time.Duration `env:"APP_RPC_TIMEOUT,required"`
-	RpcExchange string        `env:"APP_RPC_EXCHANGE,required"`
+	RpcExchange string        `env:"APP_RPC_EXCHANGE"`
+
+	// RpcTransport is "amqp", which requires RabbitMqDsn and RpcExchange, or "http", which requires RpcHttpUrl.
+	RpcTransport           string        `env:"APP_RPC_TRANSPORT" envDefault:"amqp"`
+	RpcHttpUrl             string        `env:"APP_RPC_HTTP_URL"`
+	RpcHttpEncoding        string        `env:"APP_RPC_HTTP_ENCODING" envDefault:"protobuf"`
+	RpcHttpTimeout         time.Duration `env:"APP_RPC_HTTP_TIMEOUT" envDefault:"10s"`
+	RpcHttpKeepAlive       time.Duration `env:"APP_RPC_HTTP_KEEP_ALIVE" envDefault:"30s"`
+	RpcHttpMaxIdleConns    int           `env:"APP_RPC_HTTP_MAX_IDLE_CONNS" envDefault:"100"`
+	RpcHttpIdleConnTimeout time.Duration `env:"APP_RPC_HTTP_IDLE_CONN_TIMEOUT" envDefault:"90s"`
+
+	RpcPublisherConfirms bool `env:"APP_RPC_PUBLISHER_CONFIRMS" envDefault:"false"`
+	RpcAmqpChannels      int  `env:"APP_RPC_AMQP_CHANNELS" envDefault:"4"`
+	// RpcRecordFile, if set, is where every RPC call is appended, see rpcclient.NewRecordingClient.
+	RpcRecordFile string `env:"APP_RPC_RECORD_FILE"`
+
+	// EventTransport is "sse" or "websocket", both subscribe at NchanSubUrl. Only sse multiplexes the game channels,
+	// the reconnect, buffer and backpressure settings apply to both.
+	EventTransport              string        `env:"APP_EVENT_TRANSPORT" envDefault:"sse"`
+	SseMaxChannelsPerConnection int           `env:"APP_SSE_MAX_CHANNELS_PER_CONNECTION" envDefault:"255"`
+	SseSubscribeDebounce        time.Duration `env:"APP_SSE_SUBSCRIBE_DEBOUNCE" envDefault:"100ms"`
+	SseMaxReconnects            int           `env:"APP_SSE_MAX_RECONNECTS" envDefault:"5"`
+	SseReconnectInterval        time.Duration `env:"APP_SSE_RECONNECT_INTERVAL" envDefault:"500ms"`
+	SseBufferSize               int           `env:"APP_SSE_BUFFER_SIZE" envDefault:"256"`
+	SseBackpressure             string        `env:"APP_SSE_BACKPRESSURE" envDefault:"drop"` // "block" or "drop".
+	// SseRecordFile, if set, is where the raw messages of the SseRecordChannels are appended, see sse.NewRecorder.
+	SseRecordFile     string   `env:"APP_SSE_RECORD_FILE"`
+	SseRecordChannels []string `env:"APP_SSE_RECORD_CHANNELS" envSeparator:","`
+
+	// EngineWorkers is the number of moves calculated at once over all games. 0 means one per CPU.
+	// EnginePonderShare is the percentage of them that may ponder, EngineMoveTime limits the searching engines.
+	// EngineThreads is the number of workers a searching engine uses for one move at most, if they're idle.
+	EngineWorkers     int           `env:"APP_ENGINE_WORKERS" envDefault:"0"`
+	EnginePonderShare int           `env:"APP_ENGINE_PONDER_SHARE" envDefault:"25"`
+	EngineMoveTime    time.Duration `env:"APP_ENGINE_MOVE_TIME" envDefault:"2s"`
+	EngineThreads     int           `env:"APP_ENGINE_THREADS" envDefault:"4"`
+
+	RpcBreakerFailureThreshold int           `env:"APP_RPC_BREAKER_FAILURE_THRESHOLD" envDefault:"5"`
+	RpcBreakerOpenDuration     time.Duration `env:"APP_RPC_BREAKER_OPEN_DURATION" envDefault:"30s"`
+	RpcMaxConcurrency          int           `env:"APP_RPC_MAX_CONCURRENCY" envDefault:"100"`
 }
 
 func NewConfig() (*Config, error) {
@@ -23,5 +62,27 @@ func NewConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to parse env vars: %w", err)
 	}
 
+	switch cfg.RpcTransport {
+	case "amqp":
+		if cfg.RabbitMqDsn == "" || cfg.RpcExchange == "" {
+			return nil, fmt.Errorf("APP_RABBIT_MQ_DSN and APP_RPC_EXCHANGE are required for the amqp transport")
+		}
+	case "http":
+		if cfg.RpcHttpUrl == "" {
+			return nil, fmt.Errorf("APP_RPC_HTTP_URL is required for the http transport")
+		} else if cfg.RpcHttpEncoding != "protobuf" && cfg.RpcHttpEncoding != "json" {
+			return nil, fmt.Errorf("invalid APP_RPC_HTTP_ENCODING %q", cfg.RpcHttpEncoding)
+		}
+	default:
+		return nil, fmt.Errorf("invalid APP_RPC_TRANSPORT %q", cfg.RpcTransport)
+	}
+
+	if cfg.SseBackpressure != "block" && cfg.SseBackpressure != "drop" {
+		return nil, fmt.Errorf("invalid APP_SSE_BACKPRESSURE %q", cfg.SseBackpressure)
+	}
+	if cfg.EventTransport != "sse" && cfg.EventTransport != "websocket" {
+		return nil, fmt.Errorf("invalid APP_EVENT_TRANSPORT %q", cfg.EventTransport)
+	}
+
 	return cfg, nil
 }
@@ -0,0 +1,54 @@
+package connectfour
+
+// Positions are hashed with Zobrist hashing: every (x, y, color) has a fixed pseudo-random key
+// and the hash of a position is the XOR of the keys of all its stones, starting from a key for the board size.
+// The keys are derived from the coordinates rather than drawn at startup, so hashes are stable across processes
+// and can be persisted, e.g. in opening books.
+// The side to move follows from the number of stones and doesn't need to be part of the hash.
+
+// Hash identifies the position, independent of the order of the moves that led to it.
+func (g *Game) Hash() uint64 {
+	return g.hash
+}
+
+// MirrorHash is the hash of the position mirrored at the center column.
+func (g *Game) MirrorHash() uint64 {
+	return g.mirrorHash
+}
+
+// CanonicalHash returns the same hash for a position and its mirror image.
+// If mirrored is true, the hash belongs to the mirror image and columns must be translated with MirrorColumn.
+func (g *Game) CanonicalHash() (hash uint64, mirrored bool) {
+	if g.mirrorHash < g.hash {
+		return g.mirrorHash, true
+	}
+
+	return g.hash, false
+}
+
+func (g *Game) MirrorColumn(x int) int {
+	return g.Width + 1 - x
+}
+
+// toggleStone adds or removes the stone from both hashes.
+func (g *Game) toggleStone(x int, y int, color int) {
+	g.hash ^= stoneKey(x, y, color)
+	g.mirrorHash ^= stoneKey(g.MirrorColumn(x), y, color)
+}
+
+func sizeKey(width int, height int) uint64 {
+	return splitMix64(uint64(width)<<48 | uint64(height)<<32 | 0xffff)
+}
+
+func stoneKey(x int, y int, color int) uint64 {
+	return splitMix64(uint64(x)<<32 | uint64(y)<<16 | uint64(color))
+}
+
+// splitMix64 is the finalizer of the SplitMix64 generator, which maps distinct inputs to well distributed outputs.
+func splitMix64(z uint64) uint64 {
+	z += 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+
+	return z ^ (z >> 31)
+}
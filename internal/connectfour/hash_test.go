@@ -0,0 +1,67 @@
+package connectfour
+
+import "testing"
+
+func TestHashIgnoresMoveOrder(t *testing.T) {
+	a, _ := ParseMoves(7, 6, "4453")
+	b, _ := ParseMoves(7, 6, "5344")
+	c, _ := ParseBoard("7x6 7/7/7/7/3y3/2yrr2 r")
+
+	if a.Hash() != b.Hash() || a.Hash() != c.Hash() {
+		t.Fatalf("hashes differ: %x, %x, %x", a.Hash(), b.Hash(), c.Hash())
+	}
+}
+
+func TestHashDistinguishesPositions(t *testing.T) {
+	a, _ := ParseMoves(7, 6, "44")
+	b, _ := ParseMoves(7, 6, "45")
+	c, _ := ParseMoves(7, 7, "44")
+
+	if a.Hash() == b.Hash() || a.Hash() == c.Hash() {
+		t.Fatalf("hashes collide: %x, %x, %x", a.Hash(), b.Hash(), c.Hash())
+	}
+}
+
+func TestUndoMoveRestoresHash(t *testing.T) {
+	game, _ := ParseMoves(7, 6, "445")
+	want := game.Hash()
+
+	game.ApplyMove(3, 6)
+	game.UndoMove()
+
+	if got := game.Hash(); got != want {
+		t.Fatalf("Hash = %x; want %x", got, want)
+	}
+}
+
+func TestForceMoveUpdatesHash(t *testing.T) {
+	game, _ := ParseMoves(7, 6, "44")
+	game.ForceMove(4, 5, 1)
+	want := NewGame("", "", "", 7, 6) // The board can't be reached in a game, so it's not parsed.
+	want.ForceMove(4, 6, 1)
+	want.ForceMove(4, 5, 1)
+
+	if game.Hash() != want.Hash() {
+		t.Fatalf("Hash = %x; want %x", game.Hash(), want.Hash())
+	}
+}
+
+func TestCanonicalHash(t *testing.T) {
+	game, _ := ParseMoves(7, 6, "4453")
+	mirror, _ := ParseMoves(7, 6, "4435")
+
+	if game.MirrorHash() != mirror.Hash() {
+		t.Fatalf("MirrorHash = %x; want %x", game.MirrorHash(), mirror.Hash())
+	}
+
+	hash, mirrored := game.CanonicalHash()
+	mirrorHash, mirrorMirrored := mirror.CanonicalHash()
+	if hash != mirrorHash || mirrored == mirrorMirrored {
+		t.Fatalf("CanonicalHash = %x, %v and %x, %v", hash, mirrored, mirrorHash, mirrorMirrored)
+	}
+
+	symmetric, _ := ParseMoves(7, 6, "44")
+	if symmetric.Hash() != symmetric.MirrorHash() {
+		t.Fatal("Hash != MirrorHash for a symmetric position")
+	}
+}
@@ -1,7 +1,5 @@
 package connectfour
 
-import "strconv"
-
 type Game struct {
 	GameId                string
 	ChatId                string
@@ -9,7 +7,13 @@ type Game struct {
 	WinningSequenceLength int
 	Width                 int
 	Height                int
-	moves                 map[string]Move
+	board                 []int  // The color of each cell, 0 if empty. See cellIndex.
+	moves                 []Move // In play order.
+	outcome               Outcome
+	winningCells          []Move
+	finishedAfter         int // The number of moves that led to the outcome.
+	hash                  uint64
+	mirrorHash            uint64
 }
 
 type Move struct {
@@ -18,6 +22,30 @@ type Move struct {
 	Color int // 1 (red) or 2 (yellow)
 }
 
+type Outcome int
+
+const (
+	Ongoing Outcome = iota
+	RedWon
+	YellowWon
+	Draw
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Ongoing:
+		return "ongoing"
+	case RedWon:
+		return "red_won"
+	case YellowWon:
+		return "yellow_won"
+	case Draw:
+		return "draw"
+	default:
+		return "unknown"
+	}
+}
+
 func NewGame(
 	gameId string,
 	chatId string,
@@ -32,35 +60,123 @@ func NewGame(
 		WinningSequenceLength: 4, // Although technically possible, players cannot customize this yet.
 		Width:                 width,
 		Height:                height,
-		moves:                 make(map[string]Move),
+		board:                 make([]int, width*height),
+		moves:                 make([]Move, 0, width*height),
+		hash:                  sizeKey(width, height),
+		mirrorHash:            sizeKey(width, height),
 	}
 }
 
 // ApplyMove ensures idempotency.
 func (g *Game) ApplyMove(x int, y int) bool {
-	_, ok := g.moves[g.moveKey(x, y)]
-	if ok {
+	if !g.IsInBounds(x, y) || g.HasMoveAt(x, y) {
 		return false
 	}
 
 	color, _ := g.GetCurrentPlayerColors()
-	g.moves[g.moveKey(x, y)] = Move{X: x, Y: y, Color: color}
+	mv := Move{X: x, Y: y, Color: color}
+	g.board[g.cellIndex(x, y)] = color
+	g.moves = append(g.moves, mv)
+	g.toggleStone(x, y, color)
+	g.updateOutcome(mv, len(g.moves))
 
 	return true
 }
 
+// UndoMove takes back the last move and returns it.
+func (g *Game) UndoMove() (Move, bool) {
+	mv, ok := g.LastMove()
+	if !ok {
+		return Move{}, false
+	}
+
+	g.board[g.cellIndex(mv.X, mv.Y)] = 0
+	g.moves = g.moves[:len(g.moves)-1]
+	g.toggleStone(mv.X, mv.Y, mv.Color)
+
+	if g.outcome != Ongoing && len(g.moves) < g.finishedAfter {
+		g.outcome, g.winningCells, g.finishedAfter = Ongoing, nil, 0
+	}
+
+	return mv, true
+}
+
+// Outcome is kept up to date with every move.
+// Moves played after the game is finished don't change the outcome.
+func (g *Game) Outcome() Outcome {
+	return g.outcome
+}
+
+// WinningCells returns the stones of all winning sequences, or nil if nobody has won.
+func (g *Game) WinningCells() []Move {
+	if g.winningCells == nil {
+		return nil
+	}
+
+	cells := make([]Move, len(g.winningCells))
+	copy(cells, g.winningCells)
+
+	return cells
+}
+
+func (g *Game) LastMove() (Move, bool) {
+	if len(g.moves) == 0 {
+		return Move{}, false
+	}
+
+	return g.moves[len(g.moves)-1], true
+}
+
+func (g *Game) MoveCount() int {
+	return len(g.moves)
+}
+
+// Moves returns a copy of all moves in play order.
+func (g *Game) Moves() []Move {
+	moves := make([]Move, len(g.moves))
+	copy(moves, g.moves)
+
+	return moves
+}
+
 func (g *Game) GetMoveAt(x int, y int) (Move, bool) {
-	mv, ok := g.moves[g.moveKey(x, y)]
-	return mv, ok
+	if !g.HasMoveAt(x, y) {
+		return Move{}, false
+	}
+
+	return Move{X: x, Y: y, Color: g.board[g.cellIndex(x, y)]}, true
 }
 
 func (g *Game) HasMoveAt(x int, y int) bool {
-	_, ok := g.moves[g.moveKey(x, y)]
-	return ok
+	return g.IsInBounds(x, y) && g.board[g.cellIndex(x, y)] != 0
 }
 
+// ForceMove places a stone regardless of whose turn it is, replacing any stone at the same position.
+// A replaced stone keeps its place in the move order. Colors other than 1 and 2 are ignored.
 func (g *Game) ForceMove(x int, y int, color int) {
-	g.moves[g.moveKey(x, y)] = Move{X: x, Y: y, Color: color}
+	if !g.IsInBounds(x, y) || color != 1 && color != 2 {
+		return
+	}
+
+	mv := Move{X: x, Y: y, Color: color}
+	if g.HasMoveAt(x, y) {
+		for i := range g.moves {
+			if g.moves[i].X == x && g.moves[i].Y == y {
+				g.moves[i] = mv
+			}
+		}
+		g.toggleStone(x, y, g.board[g.cellIndex(x, y)])
+		g.toggleStone(x, y, color)
+		g.board[g.cellIndex(x, y)] = color
+		g.recalculateOutcome()
+
+		return
+	}
+
+	g.board[g.cellIndex(x, y)] = color
+	g.moves = append(g.moves, mv)
+	g.toggleStone(x, y, color)
+	g.updateOutcome(mv, len(g.moves))
 }
 
 func (g *Game) IsInBounds(x int, y int) bool {
@@ -98,10 +214,10 @@ func (g *Game) NextFreeRow(x int) (int, bool) {
 }
 
 func (g *Game) Clone() *Game {
-	moves := make(map[string]Move)
-	for k, v := range g.moves {
-		moves[k] = v
-	}
+	board := make([]int, len(g.board))
+	copy(board, g.board)
+	moves := make([]Move, len(g.moves), cap(g.moves))
+	copy(moves, g.moves)
 
 	return &Game{
 		GameId:                g.GameId,
@@ -110,10 +226,46 @@ func (g *Game) Clone() *Game {
 		WinningSequenceLength: g.WinningSequenceLength,
 		Width:                 g.Width,
 		Height:                g.Height,
+		board:                 board,
 		moves:                 moves,
+		outcome:               g.outcome,
+		winningCells:          g.winningCells, // Never modified in place.
+		finishedAfter:         g.finishedAfter,
+		hash:                  g.hash,
+		mirrorHash:            g.mirrorHash,
+	}
+}
+
+// updateOutcome checks if the given move, being the n-th move played, finished the game.
+func (g *Game) updateOutcome(mv Move, n int) {
+	if g.outcome != Ongoing {
+		return
+	}
+
+	if cells := findWinningCells(g, mv.X, mv.Y, mv.Color); len(cells) > 0 {
+		g.outcome = RedWon
+		if mv.Color == 2 {
+			g.outcome = YellowWon
+		}
+		g.winningCells = cells
+		g.finishedAfter = n
+	} else if n == len(g.board) {
+		g.outcome = Draw
+		g.finishedAfter = n
+	}
+}
+
+// recalculateOutcome is needed when stones are replaced. It checks the moves in play order,
+// but against the current board.
+func (g *Game) recalculateOutcome() {
+	g.outcome, g.winningCells, g.finishedAfter = Ongoing, nil, 0
+
+	for i, mv := range g.moves {
+		g.updateOutcome(mv, i+1)
 	}
 }
 
-func (g *Game) moveKey(x int, y int) string {
-	return strconv.Itoa(x) + "," + strconv.Itoa(y)
+// cellIndex maps the 1-based coordinates to the board slice, row by row from the top.
+func (g *Game) cellIndex(x int, y int) int {
+	return (y-1)*g.Width + (x - 1)
 }
@@ -0,0 +1,155 @@
+package connectfour
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyMoveIsIdempotent(t *testing.T) {
+	game := NewGame("", "", "", 7, 6)
+
+	if ok := game.ApplyMove(4, 6); !ok {
+		t.Fatal("first ApplyMove = false; want true")
+	}
+	if ok := game.ApplyMove(4, 6); ok {
+		t.Fatal("duplicate ApplyMove = true; want false")
+	}
+	if got := game.MoveCount(); got != 1 {
+		t.Fatalf("MoveCount = %d; want 1", got)
+	}
+}
+
+func TestMovesAreInPlayOrder(t *testing.T) {
+	game := NewGame("", "", "", 7, 6)
+	game.ApplyMove(4, 6)
+	game.ApplyMove(4, 5)
+	game.ApplyMove(3, 6)
+
+	want := []Move{{X: 4, Y: 6, Color: 1}, {X: 4, Y: 5, Color: 2}, {X: 3, Y: 6, Color: 1}}
+	if got := game.Moves(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Moves = %v; want %v", got, want)
+	}
+
+	if got, ok := game.LastMove(); !ok || got != want[2] {
+		t.Fatalf("LastMove = %v, %v; want %v, true", got, ok, want[2])
+	}
+}
+
+func TestUndoMove(t *testing.T) {
+	game := NewGame("", "", "", 7, 6)
+	game.ApplyMove(4, 6)
+	game.ApplyMove(4, 5)
+
+	mv, ok := game.UndoMove()
+	if !ok || mv != (Move{X: 4, Y: 5, Color: 2}) {
+		t.Fatalf("UndoMove = %v, %v; want {4 5 2}, true", mv, ok)
+	}
+	if game.HasMoveAt(4, 5) {
+		t.Fatal("HasMoveAt(4, 5) = true after undo; want false")
+	}
+	if current, _ := game.GetCurrentPlayerColors(); current != 2 {
+		t.Fatalf("current color = %d after undo; want 2", current)
+	}
+
+	game.UndoMove()
+	if _, ok := game.UndoMove(); ok {
+		t.Fatal("UndoMove on empty game = true; want false")
+	}
+	if got := game.MoveCount(); got != 0 {
+		t.Fatalf("MoveCount = %d; want 0", got)
+	}
+}
+
+func TestForceMoveKeepsOrderWhenReplacing(t *testing.T) {
+	game := NewGame("", "", "", 7, 6)
+	game.ForceMove(1, 6, 1)
+	game.ForceMove(2, 6, 2)
+	game.ForceMove(1, 6, 2)
+
+	want := []Move{{X: 1, Y: 6, Color: 2}, {X: 2, Y: 6, Color: 2}}
+	if got := game.Moves(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Moves = %v; want %v", got, want)
+	}
+}
+
+func TestForceMoveIgnoresInvalidColors(t *testing.T) {
+	game := NewGame("", "", "", 7, 6)
+	game.ForceMove(1, 6, 1)
+	game.ForceMove(2, 6, 0)
+	game.ForceMove(3, 6, 3)
+	game.ForceMove(1, 6, -1)
+
+	want := []Move{{X: 1, Y: 6, Color: 1}}
+	if got := game.Moves(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Moves = %v; want %v", got, want)
+	}
+	if game.HasMoveAt(2, 6) || game.HasMoveAt(3, 6) {
+		t.Fatal("HasMoveAt = true; want false for invalid colors")
+	}
+	if current, _ := game.GetCurrentPlayerColors(); current != 2 {
+		t.Fatalf("current color = %d; want 2", current)
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	game := NewGame("", "", "", 7, 6)
+	game.ApplyMove(4, 6)
+
+	clone := game.Clone()
+	clone.ApplyMove(4, 5)
+	clone.UndoMove()
+	clone.UndoMove()
+
+	if got := game.MoveCount(); got != 1 {
+		t.Fatalf("MoveCount = %d; want 1", got)
+	}
+	if !game.HasMoveAt(4, 6) {
+		t.Fatal("HasMoveAt(4, 6) = false; want true")
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	cases := map[string]struct {
+		width   int
+		height  int
+		moves   string
+		outcome Outcome
+		cells   int
+	}{
+		"Ongoing":       {7, 6, "4455", Ongoing, 0},
+		"RedHorizontal": {7, 6, "4455667", RedWon, 4},
+		"YellowColumn":  {7, 6, "12121232", YellowWon, 4},
+		"Diagonal":      {7, 6, "12234334544", RedWon, 4},
+		"JoinedLines":   {7, 6, "1122335566774", RedWon, 7},
+		"Draw":          {2, 2, "1122", Draw, 0},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			game, err := ParseMoves(c.width, c.height, c.moves)
+			if err != nil {
+				t.Fatalf("ParseMoves error = %v", err)
+			}
+
+			if got := game.Outcome(); got != c.outcome {
+				t.Fatalf("Outcome = %v; want %v", got, c.outcome)
+			}
+			if got := len(game.WinningCells()); got != c.cells {
+				t.Fatalf("len(WinningCells) = %d; want %d", got, c.cells)
+			}
+		})
+	}
+}
+
+func TestUndoMoveReopensFinishedGame(t *testing.T) {
+	game, _ := ParseMoves(7, 6, "4455667")
+
+	game.UndoMove()
+
+	if got := game.Outcome(); got != Ongoing {
+		t.Fatalf("Outcome = %v; want %v", got, Ongoing)
+	}
+	if cells := game.WinningCells(); cells != nil {
+		t.Fatalf("WinningCells = %v; want nil", cells)
+	}
+}
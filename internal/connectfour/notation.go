@@ -0,0 +1,294 @@
+package connectfour
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Two notations are supported:
+//
+//   - Move sequence: one character per move naming the column, in play order, e.g. "4453".
+//     Columns 1 to 9 are written as digits, columns 10 to 35 as the letters a to z.
+//   - Board: "<width>x<height> <rows> <side to move>", e.g. "7x6 7/7/7/7/3y3/3r3 r".
+//     Rows are listed from top to bottom and separated by "/". Within a row, "r" is a red stone,
+//     "y" is a yellow stone and a number is a run of empty cells.
+
+const columnAlphabet = "123456789abcdefghijklmnopqrstuvwxyz"
+
+var ErrInvalidNotation = errors.New("invalid notation")
+
+// ParseMoves creates a game by playing the given move sequence on an empty board.
+func ParseMoves(width int, height int, moves string) (*Game, error) {
+	game := NewGame("", "", "", width, height)
+
+	for i, c := range moves {
+		x := strings.IndexRune(columnAlphabet, c) + 1
+		if x == 0 || x > width {
+			return nil, fmt.Errorf("%w: unknown column %q at move %d", ErrInvalidNotation, c, i+1)
+		}
+
+		y, ok := game.NextFreeRow(x)
+		if !ok {
+			return nil, fmt.Errorf("%w: column %d is full at move %d", ErrInvalidNotation, x, i+1)
+		}
+
+		game.ApplyMove(x, y)
+	}
+
+	return game, nil
+}
+
+// FormatMoves writes the moves of the game as a move sequence.
+func FormatMoves(g *Game) string {
+	var sb strings.Builder
+	for _, mv := range g.moves {
+		if mv.X > len(columnAlphabet) {
+			sb.WriteByte('?')
+			continue
+		}
+		sb.WriteByte(columnAlphabet[mv.X-1])
+	}
+
+	return sb.String()
+}
+
+// ParseBoard creates a game from the board notation. The board must be reachable in a game:
+// no floating stones, red started and the colors alternated.
+// The move order is one that leads to the board, not necessarily the one played.
+func ParseBoard(board string) (*Game, error) {
+	fields := strings.Fields(board)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("%w: expected size, rows and side to move", ErrInvalidNotation)
+	}
+
+	width, height, err := parseSize(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	rows := strings.Split(fields[1], "/")
+	if len(rows) != height {
+		return nil, fmt.Errorf("%w: expected %d rows, got %d", ErrInvalidNotation, height, len(rows))
+	}
+
+	colors := make([][]int, height)
+	for i, row := range rows {
+		if colors[i], err = parseRow(row, width); err != nil {
+			return nil, fmt.Errorf("%w (row %d)", err, i+1)
+		}
+	}
+
+	counts := [3]int{}
+	for y := 1; y <= height; y++ {
+		for x := 1; x <= width; x++ {
+			color := colors[y-1][x-1]
+			if color != 0 && y < height && colors[y][x-1] == 0 {
+				return nil, fmt.Errorf("%w: floating stone in column %d", ErrInvalidNotation, x)
+			}
+			counts[color]++
+		}
+	}
+	if counts[1] != counts[2] && counts[1] != counts[2]+1 {
+		return nil, fmt.Errorf("%w: %d red and %d yellow stones", ErrInvalidNotation, counts[1], counts[2])
+	}
+
+	order, ok := moveOrder(colors, counts[1]+counts[2])
+	if !ok {
+		return nil, fmt.Errorf("%w: no move order leads to the board", ErrInvalidNotation)
+	}
+	game := NewGame("", "", "", width, height)
+	for _, x := range order {
+		y, _ := game.NextFreeRow(x)
+		game.ApplyMove(x, y)
+	}
+
+	side, ok := colorFromSymbol(fields[2])
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown side to move %q", ErrInvalidNotation, fields[2])
+	}
+	if current, _ := game.GetCurrentPlayerColors(); current != side {
+		return nil, fmt.Errorf("%w: side to move doesn't match the number of stones", ErrInvalidNotation)
+	}
+
+	return game, nil
+}
+
+// FormatBoard writes the position of the game in the board notation.
+func FormatBoard(g *Game) string {
+	var sb strings.Builder
+	sb.WriteString(strconv.Itoa(g.Width) + "x" + strconv.Itoa(g.Height) + " ")
+
+	for y := 1; y <= g.Height; y++ {
+		if y > 1 {
+			sb.WriteByte('/')
+		}
+
+		empty := 0
+		for x := 1; x <= g.Width; x++ {
+			mv, ok := g.GetMoveAt(x, y)
+			if !ok {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			sb.WriteString(symbolFromColor(mv.Color))
+		}
+		if empty > 0 {
+			sb.WriteString(strconv.Itoa(empty))
+		}
+	}
+
+	current, _ := g.GetCurrentPlayerColors()
+	sb.WriteString(" " + symbolFromColor(current))
+
+	return sb.String()
+}
+
+// Render draws the board for humans, e.g. for debugging sessions and tools.
+//
+//	| . . . . . . . |
+//	| . . . R Y . . |
+//	+---------------+
+//	  1 2 3 4 5 6 7
+func Render(g *Game) string {
+	var sb strings.Builder
+
+	for y := 1; y <= g.Height; y++ {
+		sb.WriteString("|")
+		for x := 1; x <= g.Width; x++ {
+			cell := "."
+			if mv, ok := g.GetMoveAt(x, y); ok {
+				cell = strings.ToUpper(symbolFromColor(mv.Color))
+			}
+			sb.WriteString(" " + cell)
+		}
+		sb.WriteString(" |\n")
+	}
+
+	sb.WriteString("+" + strings.Repeat("-", g.Width*2+1) + "+\n ")
+	for x := 1; x <= g.Width; x++ {
+		if x <= len(columnAlphabet) {
+			sb.WriteString(" " + columnAlphabet[x-1:x])
+		} else {
+			sb.WriteString(" ?")
+		}
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// String implements fmt.Stringer so that games can be logged in the board notation.
+func (g *Game) String() string {
+	return FormatBoard(g)
+}
+
+// moveOrder finds the columns of the given number of moves that fill the rows, listed from top to bottom,
+// with alternating colors, red first. Positions that lead nowhere are remembered by their column heights.
+func moveOrder(rows [][]int, moves int) ([]int, bool) {
+	height, width := len(rows), len(rows[0])
+	heights := make([]rune, width)
+	order := make([]int, 0, moves)
+	deadEnds := make(map[string]bool)
+
+	var search func() bool
+	search = func() bool {
+		if len(order) == moves {
+			return true
+		}
+		key := string(heights)
+		if deadEnds[key] {
+			return false
+		}
+
+		color := 1 + len(order)%2
+		for x := range width {
+			if int(heights[x]) == height || rows[height-1-int(heights[x])][x] != color {
+				continue
+			}
+
+			heights[x]++
+			order = append(order, x+1)
+			if search() {
+				return true
+			}
+			order = order[:len(order)-1]
+			heights[x]--
+		}
+
+		deadEnds[key] = true
+		return false
+	}
+
+	return order, search()
+}
+
+func parseSize(size string) (int, int, error) {
+	w, h, ok := strings.Cut(size, "x")
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: size %q must be <width>x<height>", ErrInvalidNotation, size)
+	}
+
+	width, err := strconv.Atoi(w)
+	if err != nil || width < 1 {
+		return 0, 0, fmt.Errorf("%w: invalid width %q", ErrInvalidNotation, w)
+	}
+
+	height, err := strconv.Atoi(h)
+	if err != nil || height < 1 {
+		return 0, 0, fmt.Errorf("%w: invalid height %q", ErrInvalidNotation, h)
+	}
+
+	return width, height, nil
+}
+
+func parseRow(row string, width int) ([]int, error) {
+	colors := make([]int, 0, width)
+
+	for i := 0; i < len(row); i++ {
+		if color, ok := colorFromSymbol(row[i : i+1]); ok {
+			colors = append(colors, color)
+			continue
+		}
+
+		j := i
+		for j < len(row) && row[j] >= '0' && row[j] <= '9' {
+			j++
+		}
+		empty, err := strconv.Atoi(row[i:j])
+		if err != nil || empty < 1 {
+			return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidNotation, row[i:i+1])
+		}
+		colors = append(colors, make([]int, empty)...)
+		i = j - 1
+	}
+
+	if len(colors) != width {
+		return nil, fmt.Errorf("%w: expected %d cells, got %d", ErrInvalidNotation, width, len(colors))
+	}
+
+	return colors, nil
+}
+
+func colorFromSymbol(symbol string) (int, bool) {
+	switch symbol {
+	case "r":
+		return 1, true
+	case "y":
+		return 2, true
+	default:
+		return 0, false
+	}
+}
+
+func symbolFromColor(color int) string {
+	if color == 1 {
+		return "r"
+	}
+	return "y"
+}
@@ -0,0 +1,105 @@
+package connectfour
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseAndFormatMoves(t *testing.T) {
+	game, err := ParseMoves(7, 6, "4453")
+	if err != nil {
+		t.Fatalf("ParseMoves error = %v", err)
+	}
+
+	if mv, _ := game.LastMove(); mv != (Move{X: 3, Y: 6, Color: 2}) {
+		t.Fatalf("LastMove = %v; want {3 6 2}", mv)
+	}
+	if got := FormatMoves(game); got != "4453" {
+		t.Fatalf("FormatMoves = %q; want \"4453\"", got)
+	}
+	if got := FormatBoard(game); got != "7x6 7/7/7/7/3y3/2yrr2 r" {
+		t.Fatalf("FormatBoard = %q", got)
+	}
+}
+
+func TestParseMovesRejectsInvalidSequences(t *testing.T) {
+	cases := map[string]string{
+		"UnknownColumn": "48",
+		"InvalidChar":   "4-",
+		"FullColumn":    "1111111",
+	}
+
+	for name, moves := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseMoves(7, 6, moves); !errors.Is(err, ErrInvalidNotation) {
+				t.Fatalf("ParseMoves(%q) error = %v; want ErrInvalidNotation", moves, err)
+			}
+		})
+	}
+}
+
+func TestParseAndFormatBoard(t *testing.T) {
+	board := "7x6 7/7/7/3y3/2yry2/2rrry1 r"
+
+	game, err := ParseBoard(board)
+	if err != nil {
+		t.Fatalf("ParseBoard error = %v", err)
+	}
+
+	if got := FormatBoard(game); got != board {
+		t.Fatalf("FormatBoard = %q; want %q", got, board)
+	}
+	if mv, ok := game.GetMoveAt(4, 4); !ok || mv.Color != 2 {
+		t.Fatalf("GetMoveAt(4, 4) = %v, %v; want yellow", mv, ok)
+	}
+	if got := game.MoveCount(); got != 8 {
+		t.Fatalf("MoveCount = %d; want 8", got)
+	}
+
+	for i, mv := range game.Moves() {
+		if mv.Color != 1+i%2 {
+			t.Fatalf("move %d = %v; want the colors to alternate", i+1, mv)
+		}
+	}
+	for game.MoveCount() > 0 {
+		game.UndoMove()
+		if _, err := ParseBoard(FormatBoard(game)); err != nil {
+			t.Fatalf("ParseBoard after UndoMove error = %v", err)
+		}
+	}
+}
+
+func TestParseBoardRejectsInvalidBoards(t *testing.T) {
+	cases := map[string]string{
+		"MissingSide":   "7x6 7/7/7/7/7/7",
+		"InvalidSize":   "7by6 7/7/7/7/7/7 r",
+		"TooFewRows":    "7x6 7/7/7/7/7 r",
+		"TooManyCells":  "7x6 7/7/7/7/7/4r3 y",
+		"UnknownSymbol": "7x6 7/7/7/7/7/3x3 y",
+		"WrongSide":     "7x6 7/7/7/7/7/3r3 r",
+		"FloatingStone": "7x6 7/7/7/7/3r3/2y4 r",
+		"TwoRed":        "7x6 7/7/7/7/7/rr5 r",
+		"YellowFirst":   "7x6 7/7/7/7/7/y6 r",
+		"Unreachable":   "7x6 7/7/7/7/r6/y6 r",
+	}
+
+	for name, board := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseBoard(board); !errors.Is(err, ErrInvalidNotation) {
+				t.Fatalf("ParseBoard(%q) error = %v; want ErrInvalidNotation", board, err)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	game, _ := ParseMoves(4, 2, "23")
+
+	want := "| . . . . |\n" +
+		"| . R Y . |\n" +
+		"+---------+\n" +
+		"  1 2 3 4\n"
+	if got := Render(game); got != want {
+		t.Fatalf("Render =\n%s\nwant\n%s", got, want)
+	}
+}
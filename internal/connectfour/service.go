@@ -2,13 +2,10 @@ package connectfour
 
 import (
 	"context"
-	"errors"
 
-	commonv1 "github.com/gaming-platform/api/go/common/v1"
 	connectfourv1 "github.com/gaming-platform/api/go/connectfour/v1"
 	"github.com/gaming-platform/connect-four-bot/internal/api"
 	"github.com/gaming-platform/connect-four-bot/internal/rpcclient"
-	"google.golang.org/protobuf/proto"
 )
 
 type GameService struct {
@@ -29,37 +26,24 @@ func (s *GameService) OpenGame(
 	stone int32,
 	timer string,
 ) (string, error) {
-	req := connectfourv1.OpenGame{
-		PlayerId: playerId,
-		Width:    width,
-		Height:   height,
-		Stone:    connectfourv1.OpenGame_Stone(stone),
-		Timer:    timer,
-	}
-	reqBody, err := proto.Marshal(&req)
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := s.rpcClient.Call(ctx, rpcclient.Message{Name: connectfourv1.OpenGameType, Body: reqBody})
+	resp, err := api.Call[connectfourv1.OpenGameResponse](
+		ctx,
+		s.rpcClient,
+		connectfourv1.OpenGameType,
+		&connectfourv1.OpenGame{
+			PlayerId: playerId,
+			Width:    width,
+			Height:   height,
+			Stone:    connectfourv1.OpenGame_Stone(stone),
+			Timer:    timer,
+		},
+		connectfourv1.OpenGameResponseType,
+	)
 	if err != nil {
 		return "", err
 	}
 
-	switch resp.Name {
-	case connectfourv1.OpenGameResponseType:
-		var openGameResp connectfourv1.OpenGameResponse
-		err = proto.Unmarshal(resp.Body, &openGameResp)
-		if err != nil {
-			return "", err
-		}
-
-		return openGameResp.GameId, nil
-	case commonv1.ErrorResponseType:
-		return "", api.ErrorResponseToError(resp.Body)
-	default:
-		return "", errors.New("unknown response")
-	}
+	return resp.GameId, nil
 }
 
 func (s *GameService) JoinGame(
@@ -67,28 +51,15 @@ func (s *GameService) JoinGame(
 	gameId string,
 	playerId string,
 ) (*api.ErrorResponse, error) {
-	req := connectfourv1.JoinGame{
-		GameId:   gameId,
-		PlayerId: playerId,
-	}
-	reqBody, err := proto.Marshal(&req)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := s.rpcClient.Call(ctx, rpcclient.Message{Name: connectfourv1.JoinGameType, Body: reqBody})
-	if err != nil {
-		return nil, err
-	}
-
-	switch resp.Name {
-	case connectfourv1.JoinGameResponseType:
-		return nil, nil
-	case commonv1.ErrorResponseType:
-		return api.NewErrorResponse(resp.Body)
-	default:
-		return nil, errors.New("unknown response")
-	}
+	_, err := api.Call[connectfourv1.JoinGameResponse](
+		ctx,
+		s.rpcClient,
+		connectfourv1.JoinGameType,
+		&connectfourv1.JoinGame{GameId: gameId, PlayerId: playerId},
+		connectfourv1.JoinGameResponseType,
+	)
+
+	return api.AsErrorResponse(err)
 }
 
 func (s *GameService) MakeMove(
@@ -97,53 +68,25 @@ func (s *GameService) MakeMove(
 	playerId string,
 	column int32,
 ) (*api.ErrorResponse, error) {
-	req := connectfourv1.MakeMove{GameId: gameId, PlayerId: playerId, Column: column}
-	reqBody, err := proto.Marshal(&req)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := s.rpcClient.Call(ctx, rpcclient.Message{Name: connectfourv1.MakeMoveType, Body: reqBody})
-	if err != nil {
-		return nil, err
-	}
-
-	switch resp.Name {
-	case connectfourv1.MakeMoveResponseType:
-		return nil, nil
-	case commonv1.ErrorResponseType:
-		return api.NewErrorResponse(resp.Body)
-	default:
-		return nil, errors.New("unknown response")
-	}
+	_, err := api.Call[connectfourv1.MakeMoveResponse](
+		ctx,
+		s.rpcClient,
+		connectfourv1.MakeMoveType,
+		&connectfourv1.MakeMove{GameId: gameId, PlayerId: playerId, Column: column},
+		connectfourv1.MakeMoveResponseType,
+	)
+
+	return api.AsErrorResponse(err)
 }
 
 func (s *GameService) GetOpenGames(ctx context.Context, limit int32) (*connectfourv1.GetOpenGamesResponse, error) {
-	req := connectfourv1.GetOpenGames{Limit: limit}
-	reqBody, err := proto.Marshal(&req)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := s.rpcClient.Call(ctx, rpcclient.Message{Name: connectfourv1.GetOpenGamesType, Body: reqBody})
-	if err != nil {
-		return nil, err
-	}
-
-	switch resp.Name {
-	case connectfourv1.GetOpenGamesResponseType:
-		var getOpenGamesResp connectfourv1.GetOpenGamesResponse
-		err = proto.Unmarshal(resp.Body, &getOpenGamesResp)
-		if err != nil {
-			return nil, err
-		}
-
-		return &getOpenGamesResp, nil
-	case commonv1.ErrorResponseType:
-		return nil, api.ErrorResponseToError(resp.Body)
-	default:
-		return nil, errors.New("unknown response")
-	}
+	return api.Call[connectfourv1.GetOpenGamesResponse](
+		ctx,
+		s.rpcClient,
+		connectfourv1.GetOpenGamesType,
+		&connectfourv1.GetOpenGames{Limit: limit},
+		connectfourv1.GetOpenGamesResponseType,
+	)
 }
 
 func (s *GameService) GetGamesByPlayer(
@@ -153,29 +96,11 @@ func (s *GameService) GetGamesByPlayer(
 	page int32,
 	limit int32,
 ) (*connectfourv1.GetGamesByPlayerResponse, error) {
-	req := connectfourv1.GetGamesByPlayer{PlayerId: playerId, State: state, Page: page, Limit: limit}
-	reqBody, err := proto.Marshal(&req)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := s.rpcClient.Call(ctx, rpcclient.Message{Name: connectfourv1.GetGamesByPlayerType, Body: reqBody})
-	if err != nil {
-		return nil, err
-	}
-
-	switch resp.Name {
-	case connectfourv1.GetGamesByPlayerResponseType:
-		var getGamesByPlayerResp connectfourv1.GetGamesByPlayerResponse
-		err = proto.Unmarshal(resp.Body, &getGamesByPlayerResp)
-		if err != nil {
-			return nil, err
-		}
-
-		return &getGamesByPlayerResp, nil
-	case commonv1.ErrorResponseType:
-		return nil, api.ErrorResponseToError(resp.Body)
-	default:
-		return nil, errors.New("unknown response")
-	}
+	return api.Call[connectfourv1.GetGamesByPlayerResponse](
+		ctx,
+		s.rpcClient,
+		connectfourv1.GetGamesByPlayerType,
+		&connectfourv1.GetGamesByPlayer{PlayerId: playerId, State: state, Page: page, Limit: limit},
+		connectfourv1.GetGamesByPlayerResponseType,
+	)
 }
@@ -0,0 +1,74 @@
+package connectfour
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrOutOfBounds   = errors.New("out of bounds")
+	ErrFloatingStone = errors.New("floating stone")
+	ErrColumnFull    = errors.New("column full")
+	ErrWrongColor    = errors.New("wrong color")
+	ErrCellOccupied  = errors.New("cell occupied")
+	ErrGameFinished  = errors.New("game finished")
+)
+
+// MoveError describes why a move can't be applied. Use errors.Is with the Err* variables to check the reason.
+type MoveError struct {
+	Move Move
+	Err  error
+}
+
+func (e *MoveError) Error() string {
+	return fmt.Sprintf("invalid move x=%d y=%d color=%d: %v", e.Move.X, e.Move.Y, e.Move.Color, e.Err)
+}
+
+func (e *MoveError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateMove checks if the move follows the rules in the current position.
+// A color of 0 skips the color check. A move that's already on the board is valid, see PlayMove.
+// A move above the top of a full column is ErrColumnFull, above the top of any other column ErrOutOfBounds.
+func (g *Game) ValidateMove(x int, y int, color int) error {
+	mv := Move{X: x, Y: y, Color: color}
+
+	if !g.IsInBounds(x, y) {
+		if _, free := g.NextFreeRow(x); y < 1 && g.IsInBounds(x, 1) && !free {
+			return &MoveError{Move: mv, Err: ErrColumnFull}
+		}
+		return &MoveError{Move: mv, Err: ErrOutOfBounds}
+	}
+
+	if existing, ok := g.GetMoveAt(x, y); ok {
+		if color == 0 || existing.Color == color {
+			return nil
+		}
+		return &MoveError{Move: mv, Err: ErrCellOccupied}
+	}
+
+	if g.outcome != Ongoing {
+		return &MoveError{Move: mv, Err: ErrGameFinished}
+	}
+
+	if freeY, _ := g.NextFreeRow(x); y != freeY {
+		return &MoveError{Move: mv, Err: ErrFloatingStone}
+	}
+
+	if current, _ := g.GetCurrentPlayerColors(); color != 0 && color != current {
+		return &MoveError{Move: mv, Err: ErrWrongColor}
+	}
+
+	return nil
+}
+
+// PlayMove is the validating counterpart of ApplyMove. It returns false without an error
+// if the move is already on the board, so that duplicate moves can be ignored.
+func (g *Game) PlayMove(x int, y int, color int) (bool, error) {
+	if err := g.ValidateMove(x, y, color); err != nil {
+		return false, err
+	}
+
+	return g.ApplyMove(x, y), nil
+}
@@ -0,0 +1,48 @@
+package connectfour
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPlayMove(t *testing.T) {
+	cases := map[string]struct {
+		moves   string
+		x, y    int
+		color   int
+		applied bool
+		err     error
+	}{
+		"Valid":          {"44", 4, 4, 1, true, nil},
+		"UnknownColor":   {"44", 3, 6, 0, true, nil},
+		"Duplicate":      {"44", 4, 5, 2, false, nil},
+		"OldDuplicate":   {"4433", 4, 6, 0, false, nil},
+		"OutOfBounds":    {"44", 8, 6, 1, false, ErrOutOfBounds},
+		"FloatingStone":  {"44", 3, 5, 1, false, ErrFloatingStone},
+		"ColumnFull":     {"444444", 4, 0, 1, false, ErrColumnFull},
+		"AboveColumn":    {"44", 4, 0, 1, false, ErrOutOfBounds},
+		"WrongColor":     {"44", 3, 6, 2, false, ErrWrongColor},
+		"CellOccupied":   {"44", 4, 6, 2, false, ErrCellOccupied},
+		"FullColumnCell": {"444444", 4, 1, 1, false, ErrCellOccupied},
+		"GameFinished":   {"1212121", 3, 6, 2, false, ErrGameFinished},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			game, err := ParseMoves(7, 6, c.moves)
+			if err != nil {
+				t.Fatalf("ParseMoves error = %v", err)
+			}
+
+			applied, err := game.PlayMove(c.x, c.y, c.color)
+			if applied != c.applied || !errors.Is(err, c.err) {
+				t.Fatalf("PlayMove = %v, %v; want %v, %v", applied, err, c.applied, c.err)
+			}
+
+			var moveErr *MoveError
+			if c.err != nil && !errors.As(err, &moveErr) {
+				t.Fatalf("PlayMove error = %T; want *MoveError", err)
+			}
+		})
+	}
+}
@@ -1,13 +1,13 @@
 package connectfour
 
-func IsWinningMove(g *Game, x, y, color int) bool {
-	directions := [][2]int{
-		{1, 0},  // horizontal
-		{0, 1},  // vertical
-		{1, 1},  // diagonal \
-		{1, -1}, // diagonal /
-	}
+var directions = [][2]int{
+	{1, 0},  // horizontal
+	{0, 1},  // vertical
+	{1, 1},  // diagonal \
+	{1, -1}, // diagonal /
+}
 
+func IsWinningMove(g *Game, x, y, color int) bool {
 	for _, d := range directions {
 		count := 1
 		count += countDirection(g, x, y, d[0], d[1], color)
@@ -21,6 +21,32 @@ func IsWinningMove(g *Game, x, y, color int) bool {
 	return false
 }
 
+// findWinningCells returns the stones of all winning sequences through the given position,
+// including the position itself, or nil if there's none.
+func findWinningCells(g *Game, x, y, color int) []Move {
+	var cells []Move
+
+	for _, d := range directions {
+		forward := countDirection(g, x, y, d[0], d[1], color)
+		backward := countDirection(g, x, y, -d[0], -d[1], color)
+
+		if 1+forward+backward < g.WinningSequenceLength {
+			continue
+		}
+
+		if cells == nil {
+			cells = append(cells, Move{X: x, Y: y, Color: color})
+		}
+		for i := -backward; i <= forward; i++ {
+			if i != 0 {
+				cells = append(cells, Move{X: x + i*d[0], Y: y + i*d[1], Color: color})
+			}
+		}
+	}
+
+	return cells
+}
+
 func countDirection(g *Game, x, y, dx, dy, color int) int {
 	count := 0
 	for i := 1; ; i++ {
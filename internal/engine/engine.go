@@ -1,5 +1,21 @@
 package engine
 
-import "github.com/gaming-platform/connect-four-bot/internal/connectfour"
+import (
+	"context"
 
-type CalculateNextMove func(game *connectfour.Game) (int, bool)
+	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
+)
+
+// CalculateNextMove returns the column to play. Engines that search for long should give up once ctx is done,
+// the result is discarded then.
+type CalculateNextMove func(ctx context.Context, game *connectfour.Game) (int, bool)
+
+// Budget hands out extra threads to engines that search in parallel, see Pool.
+type Budget interface {
+	// TryAcquire takes up to n threads without waiting and returns how many it got, and the function to give them back.
+	TryAcquire(n int) (int, func())
+}
+
+// Ponder searches ahead on the opponent's time, so that the engine answers quicker once the opponent moved.
+// It returns once ctx is done or there's nothing left to search.
+type Ponder func(ctx context.Context, game *connectfour.Game)
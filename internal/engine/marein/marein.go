@@ -1,6 +1,7 @@
 package engine_marein
 
 import (
+	"context"
 	"math"
 	"math/rand"
 
@@ -24,8 +25,9 @@ func NewOptions(
 	}
 }
 
-func CreateCalculateNextMove(options Options) func(game *connectfour.Game) (int, bool) {
-	return func(game *connectfour.Game) (int, bool) {
+// CreateCalculateNextMove returns the engine. It's fast enough to not look at ctx.
+func CreateCalculateNextMove(options Options) func(ctx context.Context, game *connectfour.Game) (int, bool) {
+	return func(ctx context.Context, game *connectfour.Game) (int, bool) {
 		return calculateNextMove(game, options)
 	}
 }
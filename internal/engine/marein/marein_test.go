@@ -1,8 +1,6 @@
 package engine_marein
 
 import (
-	"strconv"
-	"strings"
 	"testing"
 
 	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
@@ -18,174 +16,89 @@ type boardCase struct {
 func TestBoardCases(t *testing.T) {
 	boardCases := map[string]boardCase{
 		"WinIfPossible": {
-			board: `0 0 0 0 0 0 0
-					0 0 0 0 0 0 0
-					0 0 0 0 0 0 0
-					1 0 0 0 0 0 0
-					1 0 0 0 0 0 0
-					1 2 2 2 0 0 0`,
+			board:   "7x6 7/7/7/r6/r6/ryyy3 r",
 			allowed: []int{1},
 		},
 		"PreventWin": {
-			board: `0 0 0 0 0 0 0
-					1 0 0 0 0 0 0
-					2 0 0 0 0 0 0
-					1 0 0 0 0 0 0
-					1 0 0 0 0 0 0
-					1 2 2 2 0 0 0`,
+			board:   "7x6 7/r6/y6/r6/r6/ryyy3 r",
 			allowed: []int{5},
 		},
 		"IgnoreLosingColumns": {
-			board: `0 0 0 0 0 0 0
-					0 0 0 0 0 0 0
-					0 0 1 0 0 0 0
-					0 0 1 0 0 0 0
-					0 0 2 2 2 0 0
-					0 0 1 1 2 0 0`,
+			board:   "7x6 7/7/2r4/2r4/2yyy2/2rry2 r",
 			allowed: []int{1, 3, 4, 5, 7},
 		},
 		"IgnoreColumnsCreatingFork": {
-			// X would lead to a fork Y.
-			board: `0 0 0 0 0 0 0
-					0 0 0 0 0 0 0
-					0 0 X 2 1 0 0
-					0 0 1 1 2 0 0
-					0 0 2 2 1 Y 0
-					0 0 1 2 1 2 0`,
+			// Column 3 would lead to a fork at (6, 5).
+			board:   "7x6 7/7/3yr2/2rry2/2yyr2/2ryry1 r",
 			allowed: []int{1, 2, 4, 5, 6, 7},
 		},
 		"UseFirstWhenOnlyOptionIsLosing": {
-			board: `0 1 2 2 2 1 0
-					0 2 1 2 2 2 0
-					0 2 2 2 1 1 0
-					0 1 1 1 2 1 0
-					0 2 1 2 1 1 1
-					2 2 1 1 1 2 1`,
+			board:   "7x6 1ryyyr1/1yryyy1/1yyyrr1/1rrryr1/1yryrrr/yyrrryr y",
 			allowed: []int{1},
 		},
 		"CreateHorizontalFork1": {
-			board:   `0 X 1 1 0 2 2`,
+			board:   "7x1 2rr1yy r",
 			allowed: []int{2},
 		},
 		"PreventHorizontalFork1": {
-			board:   `0 X 1 1 0 2 0`,
+			board:   "7x1 2rr1y1 y",
 			allowed: []int{2},
 		},
 		"CreateHorizontalFork2": {
-			board: `0 0 0 0 0 0 0
-					0 0 0 0 0 0 2
-					0 X 2 0 0 0 1
-					0 1 1 0 0 0 2
-					0 1 1 1 0 2 2
-					0 2 1 1 0 2 2`,
+			board:   "7x6 7/6y/2y3r/1rr3y/1rrr1yy/1yrr1yy r",
 			allowed: []int{2},
 		},
 		"PreventHorizontalFork2": {
-			board: `0 0 0 0 0 0 0
-					0 0 0 0 0 0 0
-					0 X 2 0 0 2 1
-					0 1 1 0 0 1 2
-					0 1 1 1 0 2 2
-					0 2 1 1 0 2 2`,
+			board:   "7x6 7/7/2y2yr/1rr2ry/1rrr1yy/1yrr1yy y",
 			allowed: []int{2},
 		},
 		"CreateHorizontalFork3": {
-			board: `0 0 0 0 0 0 0
-					0 0 0 1 0 0 0
-					0 0 0 2 2 0 0
-					0 0 X 1 1 0 0
-					0 0 1 2 1 1 0
-					0 0 2 1 2 1 2`,
+			board:   "7x6 7/3r3/3yy2/3rr2/2ryrry/y1yryry r",
 			allowed: []int{3},
 		},
 		"PreventHorizontalFork3": {
-			// Y would prevent the fork, but would lose immediately.
-			board: `0 0 0 2 0 0 0
-					0 0 0 1 0 0 0
-					0 0 0 2 2 0 0
-					0 0 Y 1 1 X 0
-					0 0 1 2 1 1 0
-					0 0 2 1 2 1 2`,
+			// Column 3 would prevent the fork, but would lose immediately.
+			board:   "7x6 3y3/3r3/3yy2/3rr2/2ryrr1/2yryry y",
 			allowed: []int{6},
 		},
 		"CreateVerticalFork1": {
-			board: `0 0 0 2 0 0 0
-					0 0 0 2 0 0 0
-					0 0 0 1 0 0 0
-					0 0 1 2 1 0 0
-					2 0 1 2 2 0 0
-					1 0 2 1 1 0 X`,
+			board:   "7x6 3y3/3y3/3r3/2ryr2/y1ryy2/r1yrr2 r",
 			allowed: []int{7},
 		},
 		"PreventVerticalFork1": {
-			board: `0 0 0 2 0 0 0
-					0 0 0 2 0 0 0
-					0 0 0 1 0 0 0
-					0 0 1 2 1 0 0
-					0 0 1 2 2 0 0
-					1 0 2 1 1 0 X`,
+			board:   "7x6 3y3/3y3/3r3/2ryr2/2ryy2/r1yrr2 y",
 			allowed: []int{7},
 		},
 		"CreateVerticalFork2": {
-			board: `0 0 0 1 2 0 0
-					0 0 1 2 2 0 0
-					0 0 2 2 2 0 0
-					0 0 1 2 1 0 0
-					0 X 1 1 2 1 0
-					2 1 1 1 2 1 0`,
+			board:   "7x6 3ry2/2ryy2/2yyy2/2ryr2/2rryr1/yrrryr1 y",
 			allowed: []int{2},
 		},
 		"CreateVerticalFork3": {
-			// Y would be a fork, but would lose immediately.
-			board: `0 0 0 2 0 0 0
-					0 0 2 2 2 0 0
-					0 0 2 2 2 0 0
-					0 Y 1 2 1 0 0
-					X 1 2 1 1 0 0
-					1 1 2 1 1 0 0`,
+			// Column 2 would be a fork, but would lose immediately.
+			board:   "7x6 2ryr2/2yyy2/2yyy2/2ryr2/1ryrr2/rryrr2 y",
 			allowed: []int{1},
 		},
 		"DoNotCreateLosingFork1": {
-			board: `0 0 1 2 0 0 0
-					0 0 2 1 1 0 0
-					0 0 1 1 1 0 0
-					0 X 2 1 2 0 0
-					0 2 1 2 1 0 0
-					0 2 2 1 2 0 0`,
+			board:   "7x6 2ry3/2yrr2/2rrr2/2yry2/1yryr2/1yyry2 y",
 			allowed: []int{1, 5, 6, 7},
 		},
 		"DoNotCreateLosingFork2": {
-			board: `0 0 0 1 0 0 0
-					0 0 0 1 0 0 0
-					0 0 0 1 2 0 0
-					0 2 X 2 1 0 0
-					0 1 2 1 2 1 0
-					0 1 2 1 2 2 2`,
+			board:   "7x6 3r3/3r3/3ry2/1y1yr2/1ryryr1/1ryryyy r",
 			allowed: []int{1, 2, 5, 6, 7},
 		},
 		"NoMoreMoves": {
-			board:   `1 2 1 2 1 2 1`,
+			board:   "7x1 ryryryr y",
 			allowed: []int{0},
 		},
 		//"ForcingMoveLeadsToFork1": {
-		//	// X forces Y, then Z can create a fork.
-		//	board: `0 0 0 1 2 0 0
-		//			0 0 0 1 1 0 0
-		//			0 0 0 1 1 2 1
-		//			0 0 0 2 2 1 2
-		//			0 Z 1 1 2 2 2
-		//			Y X 1 1 2 2 2`,
+		//	// Column 2 forces column 1, then column 2 again can create a fork.
+		//	board:   "7x6 3ry2/3rr2/3rryr/3yyry/2rryyy/2rryyy r",
 		//	allowed: []int{2},
 		//},
 		//"ForcingMoveLeadsToFork2": {
-		//	// X forces Y, then Z creates a future fork.
-		//	// A, B, C, D shows an example continuation.
-		//	board: `0 0 2 1 2 0 0
-		//			0 0 1 1 2 A D
-		//			0 0 2 1 1 Z C
-		//			0 0 2 2 1 X B
-		//			0 0 1 1 1 2 Y
-		//			2 0 2 1 2 1 2`,
+		//	// Column 6 forces column 7, then column 6 again creates a future fork.
+		//	// An example continuation is 6, 7, 7, 7.
+		//	board:   "7x6 2yry2/2rry2/2yrr2/2yyr2/2rrry1/y1yryry r",
 		//	allowed: []int{6},
 		//},
 	}
@@ -198,7 +111,10 @@ func TestBoardCases(t *testing.T) {
 func runBoardCase(t *testing.T, c boardCase) {
 	t.Helper()
 	for i := 0; i < iterationsPerCase; i++ {
-		game := newGameFromAscii(c.board)
+		game, err := connectfour.ParseBoard(c.board)
+		if err != nil {
+			t.Fatalf("invalid board %q: %v", c.board, err)
+		}
 		x, ok := calculateNextMove(game, NewOptions(100))
 
 		found := false
@@ -213,28 +129,3 @@ func runBoardCase(t *testing.T, c boardCase) {
 		}
 	}
 }
-
-func newGameFromAscii(board string) *connectfour.Game {
-	lines := make([]string, 0)
-	for _, l := range strings.Split(strings.TrimSpace(board), "\n") {
-		lines = append(lines, strings.TrimSpace(l))
-	}
-
-	fields := strings.Fields(lines[0])
-
-	game := connectfour.NewGame("", "", "", len(fields), len(lines))
-
-	for y, line := range lines {
-		fields := strings.Fields(line)
-		for x, color := range fields {
-			color, err := strconv.Atoi(color)
-			if err != nil || color == 0 {
-				continue
-			}
-
-			game.ForceMove(x+1, y+1, color)
-		}
-	}
-
-	return game
-}
@@ -0,0 +1,319 @@
+package engine_negamax
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
+	"github.com/gaming-platform/connect-four-bot/internal/engine"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// A depth-limited alpha-beta search with iterative deepening and a transposition table.
+// Positions beyond the depth are scored by counting the open lines of both players.
+// The table is shared by all games, which lets the bot ponder: while the opponent thinks,
+// the likely replies are searched ahead, and the real search afterwards finds them prepared.
+// Moves are searched in parallel with lazy SMP: helper threads search the same position at staggered depths
+// and only share their results through the table, which lets the main thread cut off more of the tree.
+
+var ponderLookupsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "connect_four_bot_engine_ponder_lookups_total",
+	Help: "The number of moves the negamax engine looked up before searching, by whether they were prepared.",
+}, []string{"result"})
+
+const (
+	winScore = 1_000_000 // Minus the number of moves until the win, so that quicker wins score higher.
+	infinity = math.MaxInt32
+	// checkEvery is the number of nodes between checks whether the search should stop.
+	checkEvery = 1024
+)
+
+// lineWeights scores a line of four cells with the given number of stones of one player and none of the other.
+var lineWeights = [...]int{0, 1, 4, 16}
+
+type Options struct {
+	MaxDepth  int           // The number of moves to look ahead. 0 means 10.
+	MoveTime  time.Duration // The time to search for a move. 0 means until MaxDepth is reached.
+	TableSize int           // The number of positions the table holds. 0 means 1<<20.
+	// Threads is the number of threads a move is searched with at most, the caller's included.
+	// The others are taken from the Budget, as far as it has some to spare. 0 means 1.
+	Threads int
+	Budget  engine.Budget
+}
+
+type Engine struct {
+	options Options
+	table   *table
+}
+
+func NewEngine(options Options) *Engine {
+	if options.MaxDepth <= 0 {
+		options.MaxDepth = 10
+	}
+	if options.TableSize <= 0 {
+		options.TableSize = 1 << 20
+	}
+
+	return &Engine{options: options, table: newTable(options.TableSize)}
+}
+
+// CalculateNextMove implements engine.CalculateNextMove. It stops early once ctx is done.
+func (e *Engine) CalculateNextMove(ctx context.Context, game *connectfour.Game) (int, bool) {
+	g := game.Clone()
+	if g.Outcome() != connectfour.Ongoing || len(g.GetAvailableColumns()) == 0 {
+		return 0, false
+	}
+
+	if x, ok := e.prepared(g); ok {
+		ponderLookupsCounter.With(map[string]string{"result": "prepared"}).Inc()
+		return x, true
+	}
+	ponderLookupsCounter.With(map[string]string{"result": "searched"}).Inc()
+
+	var deadline time.Time
+	if e.options.MoveTime > 0 {
+		deadline = time.Now().Add(e.options.MoveTime)
+	}
+
+	return e.search(ctx, g, e.options.MaxDepth, deadline, e.options.Threads), true
+}
+
+// Ponder searches the replies to the opponent's likely moves until ctx is done, the most likely first.
+// The game must be at the opponent's turn.
+func (e *Engine) Ponder(ctx context.Context, game *connectfour.Game) {
+	g := game.Clone()
+	if g.Outcome() != connectfour.Ongoing {
+		return
+	}
+
+	// A shallow search from the opponent's point of view predicts the reply.
+	predicted := e.search(ctx, g, min(e.options.MaxDepth, 6), time.Time{}, 1)
+	replies := append([]int{predicted}, slices.DeleteFunc(centerFirst(g, 0), func(x int) bool { return x == predicted })...)
+
+	for _, x := range replies {
+		if ctx.Err() != nil {
+			return
+		}
+		y, ok := g.NextFreeRow(x)
+		if !ok {
+			continue
+		}
+
+		g.ApplyMove(x, y)
+		if g.Outcome() == connectfour.Ongoing {
+			e.search(ctx, g, e.options.MaxDepth, time.Time{}, 1)
+		}
+		g.UndoMove()
+	}
+}
+
+// prepared returns the move if the position was searched to the full depth before, e.g. while pondering.
+// A proven win or loss is final at any depth, because deepen stops there.
+func (e *Engine) prepared(g *connectfour.Game) (int, bool) {
+	en, ok := e.table.load(g.Hash())
+	if !ok || en.bound != boundExact || en.move == 0 {
+		return 0, false
+	}
+	if int(en.depth) < e.options.MaxDepth && !isProven(g, int(en.score)) {
+		return 0, false
+	}
+	if _, ok := g.NextFreeRow(int(en.move)); !ok {
+		return 0, false
+	}
+
+	return int(en.move), true
+}
+
+// search deepens until maxDepth, the deadline or ctx is reached and returns the best move of the deepest
+// completed iteration. It returns a center column if not even the first iteration completed.
+// Up to threads-1 helpers search along until the main thread is done.
+func (e *Engine) search(ctx context.Context, g *connectfour.Game, maxDepth int, deadline time.Time, threads int) int {
+	if threads > 1 && e.options.Budget != nil {
+		helpers, release := e.options.Budget.TryAcquire(threads - 1)
+		defer release()
+
+		helperCtx, cancelHelpers := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		defer cancelHelpers()
+		for i := range helpers {
+			wg.Add(1)
+			go func(g *connectfour.Game) {
+				defer wg.Done()
+				s := &searcher{ctx: helperCtx, deadline: deadline, table: e.table, game: g}
+				s.deepen(1+(i+1)%2, maxDepth) // Every other helper starts one ply deeper.
+			}(g.Clone())
+		}
+	}
+
+	s := &searcher{ctx: ctx, deadline: deadline, table: e.table, game: g}
+	best, ok := s.deepen(1, maxDepth)
+	if !ok {
+		best = centerFirst(g, 0)[0]
+	}
+
+	return best
+}
+
+// searcher holds the state of one search. The game is changed during the search and restored afterwards.
+type searcher struct {
+	ctx      context.Context
+	deadline time.Time
+	table    *table
+	game     *connectfour.Game
+	nodes    int
+	stopped  bool
+}
+
+// deepen searches with increasing depth and returns the best move of the deepest completed iteration.
+func (s *searcher) deepen(startDepth int, maxDepth int) (int, bool) {
+	best, ok := 0, false
+	for depth := startDepth; depth <= maxDepth; depth++ {
+		score, x := s.negamax(depth, -infinity, infinity, true)
+		if s.stopped {
+			break
+		}
+		best, ok = x, true
+		if isProven(s.game, score) {
+			break // The outcome is certain, deeper searches won't change it.
+		}
+	}
+
+	return best, ok
+}
+
+// negamax returns the score of the position from the view of the player to move, and the best move at the root.
+func (s *searcher) negamax(depth int, alpha int, beta int, root bool) (int, int) {
+	s.nodes++
+	if s.nodes%checkEvery == 0 && (s.ctx.Err() != nil || !s.deadline.IsZero() && time.Now().After(s.deadline)) {
+		s.stopped = true
+	}
+	if s.stopped {
+		return 0, 0
+	}
+
+	g := s.game
+	switch g.Outcome() {
+	case connectfour.Draw:
+		return 0, 0
+	case connectfour.RedWon, connectfour.YellowWon:
+		return -(winScore - g.MoveCount()), 0 // The previous move won.
+	}
+	if depth == 0 {
+		return evaluate(g), 0
+	}
+
+	hash := g.Hash()
+	ttMove := 0
+	if en, ok := s.table.load(hash); ok {
+		ttMove = int(en.move)
+		if int(en.depth) >= depth && !root {
+			score := int(en.score)
+			switch {
+			case en.bound == boundExact,
+				en.bound == boundLower && score >= beta,
+				en.bound == boundUpper && score <= alpha:
+				return score, ttMove
+			}
+		}
+	}
+
+	alphaOrig := alpha
+	best, bestMove := -infinity, 0
+	for _, x := range centerFirst(g, ttMove) {
+		y, _ := g.NextFreeRow(x)
+		g.ApplyMove(x, y)
+		score, _ := s.negamax(depth-1, -beta, -alpha, false)
+		score = -score
+		g.UndoMove()
+		if s.stopped {
+			return 0, 0
+		}
+
+		if score > best {
+			best, bestMove = score, x
+		}
+		alpha = max(alpha, best)
+		if alpha >= beta {
+			break
+		}
+	}
+
+	bound := uint8(boundExact)
+	if best <= alphaOrig {
+		bound = boundUpper
+	} else if best >= beta {
+		bound = boundLower
+	}
+	s.table.store(hash, entry{score: int32(best), depth: uint8(depth), bound: bound, move: uint8(bestMove)})
+
+	return best, bestMove
+}
+
+// centerFirst returns the available columns, first the given one, then the closer to the center the earlier.
+func centerFirst(g *connectfour.Game, first int) []int {
+	columns := g.GetAvailableColumns()
+	center := float64(g.Width+1) / 2
+	slices.SortStableFunc(columns, func(a, b int) int {
+		if a == first || b == first {
+			return boolToInt(b == first) - boolToInt(a == first)
+		}
+		return int(math.Abs(float64(a)-center)*2) - int(math.Abs(float64(b)-center)*2)
+	})
+
+	return columns
+}
+
+// evaluate counts the lines of four that only one player has stones in, from the view of the player to move.
+func evaluate(g *connectfour.Game) int {
+	current, _ := g.GetCurrentPlayerColors()
+	score := 0
+
+	for y := 1; y <= g.Height; y++ {
+		for x := 1; x <= g.Width; x++ {
+			for _, d := range [...][2]int{{1, 0}, {0, 1}, {1, 1}, {1, -1}} {
+				if !g.IsInBounds(x+3*d[0], y+3*d[1]) {
+					continue
+				}
+
+				counts := [3]int{}
+				for i := range 4 {
+					mv, _ := g.GetMoveAt(x+i*d[0], y+i*d[1])
+					counts[mv.Color]++
+				}
+				switch {
+				case counts[1] > 0 && counts[2] > 0:
+				case counts[current] > 0:
+					score += lineWeights[counts[current]]
+				default:
+					score -= lineWeights[counts[3-current]]
+				}
+			}
+		}
+	}
+
+	return score
+}
+
+// isProven reports whether the score is a win or loss found by the search rather than an evaluation.
+func isProven(g *connectfour.Game, score int) bool {
+	return abs(score) > winScore-g.Width*g.Height
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
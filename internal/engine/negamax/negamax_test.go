@@ -0,0 +1,171 @@
+package engine_negamax
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestBoardCases(t *testing.T) {
+	boardCases := map[string]struct {
+		board   string
+		allowed []int
+	}{
+		"WinIfPossible": {
+			board:   "7x6 7/7/7/r6/r6/ryyy3 r",
+			allowed: []int{1},
+		},
+		"PreventWin": {
+			board:   "7x6 7/r6/y6/r6/r6/ryyy3 r",
+			allowed: []int{5},
+		},
+		"PreventFork": {
+			// Yellow would threaten both ends of the row.
+			board:   "7x6 7/7/7/7/7/2yy1rr r",
+			allowed: []int{1, 2, 5},
+		},
+		"OnlyColumn": {
+			board:   "3x2 yr1/ry1 r",
+			allowed: []int{3},
+		},
+	}
+
+	for name, c := range boardCases {
+		t.Run(name, func(t *testing.T) {
+			game, err := connectfour.ParseBoard(c.board)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			x, ok := NewEngine(Options{MaxDepth: 6}).CalculateNextMove(context.Background(), game)
+			if !ok || !slices.Contains(c.allowed, x) {
+				t.Fatalf("move %d, %v; want one of %v", x, ok, c.allowed)
+			}
+		})
+	}
+}
+
+func TestNoMoveOnFinishedGame(t *testing.T) {
+	game, _ := connectfour.ParseMoves(1, 1, "1")
+
+	if x, ok := NewEngine(Options{}).CalculateNextMove(context.Background(), game); ok {
+		t.Fatalf("move %d on a full board; want none", x)
+	}
+}
+
+func TestSearchStopsWhenCancelled(t *testing.T) {
+	game := connectfour.NewGame("g", "", "", 7, 6)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	x, ok := NewEngine(Options{MaxDepth: 42}).CalculateNextMove(ctx, game)
+	if time.Since(start) > time.Second {
+		t.Fatalf("search took %v after cancellation", time.Since(start))
+	}
+	if !ok || x < 1 || x > 7 {
+		t.Fatalf("move %d, %v; want a legal move", x, ok)
+	}
+}
+
+func TestSearchStopsAfterMoveTime(t *testing.T) {
+	game := connectfour.NewGame("g", "", "", 7, 6)
+
+	start := time.Now()
+	if _, ok := NewEngine(Options{MaxDepth: 42, MoveTime: 20 * time.Millisecond}).CalculateNextMove(context.Background(), game); !ok {
+		t.Fatal("no move; want the best move found in time")
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("search took %v; want it to stop after the move time", time.Since(start))
+	}
+}
+
+func TestPonderPreparesReplies(t *testing.T) {
+	for name, moves := range map[string]string{
+		"Evaluated": "44",
+		// Red wins after any reply, which is proven long before the full depth.
+		"ProvenWin": "44553",
+	} {
+		t.Run(name, func(t *testing.T) {
+			e := NewEngine(Options{MaxDepth: 6})
+			game, _ := connectfour.ParseMoves(7, 6, moves) // The opponent is to move.
+			e.Ponder(context.Background(), game)
+
+			for x := 1; x <= 7; x++ {
+				before := testutil.ToFloat64(ponderLookupsCounter.WithLabelValues("prepared"))
+				reply := game.Clone()
+				y, _ := reply.NextFreeRow(x)
+				reply.ApplyMove(x, y)
+
+				if _, ok := e.CalculateNextMove(context.Background(), reply); !ok {
+					t.Fatalf("no move after reply %d", x)
+				}
+				if n := testutil.ToFloat64(ponderLookupsCounter.WithLabelValues("prepared")) - before; n != 1 {
+					t.Errorf("reply %d wasn't prepared", x)
+				}
+			}
+		})
+	}
+}
+
+func TestPonderStopsWhenCancelled(t *testing.T) {
+	game, _ := connectfour.ParseMoves(7, 6, "4")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	NewEngine(Options{MaxDepth: 42}).Ponder(ctx, game)
+	if time.Since(start) > time.Second {
+		t.Fatalf("pondering took %v after cancellation", time.Since(start))
+	}
+}
+
+// countingBudget lends the threads it has and counts those not given back.
+type countingBudget struct {
+	threads int
+	lent    atomic.Int32
+}
+
+func (b *countingBudget) TryAcquire(n int) (int, func()) {
+	n = min(n, b.threads)
+	b.lent.Add(int32(n))
+
+	return n, func() { b.lent.Add(-int32(n)) }
+}
+
+func TestParallelSearchFindsSameMoves(t *testing.T) {
+	budget := &countingBudget{threads: 3}
+	e := NewEngine(Options{MaxDepth: 8, Threads: 4, Budget: budget})
+
+	for board, want := range map[string]int{
+		"7x6 7/7/7/r6/r6/ryyy3 r":   1,
+		"7x6 7/r6/y6/r6/r6/ryyy3 r": 5,
+	} {
+		game, _ := connectfour.ParseBoard(board)
+		if x, ok := e.CalculateNextMove(context.Background(), game); !ok || x != want {
+			t.Errorf("move on %s = %d, %v; want %d", board, x, ok, want)
+		}
+	}
+	if n := budget.lent.Load(); n != 0 {
+		t.Fatalf("%d threads not given back after the search", n)
+	}
+}
+
+func TestParallelSearchGivesBackThreadsWhenCancelled(t *testing.T) {
+	budget := &countingBudget{threads: 3}
+	game := connectfour.NewGame("g", "", "", 7, 6)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, ok := NewEngine(Options{MaxDepth: 42, Threads: 4, Budget: budget}).CalculateNextMove(ctx, game); !ok {
+		t.Fatal("no move; want the best move found in time")
+	}
+	if n := budget.lent.Load(); n != 0 {
+		t.Fatalf("%d threads not given back after the search", n)
+	}
+}
@@ -0,0 +1,75 @@
+package engine_negamax
+
+import "sync/atomic"
+
+const (
+	boundExact = iota
+	boundLower // The score is at least this, the search was cut off.
+	boundUpper // The score is at most this, no move reached alpha.
+)
+
+// entry is the result of searching a position.
+type entry struct {
+	score int32
+	depth uint8
+	bound uint8
+	move  uint8 // The best column, 0 if unknown.
+}
+
+func (e entry) pack() uint64 {
+	return uint64(uint32(e.score)) | uint64(e.depth)<<32 | uint64(e.bound)<<40 | uint64(e.move)<<48
+}
+
+func unpack(data uint64) entry {
+	return entry{
+		score: int32(uint32(data)),
+		depth: uint8(data >> 32),
+		bound: uint8(data >> 40),
+		move:  uint8(data >> 48),
+	}
+}
+
+// table is a transposition table that is shared by concurrent searches without locking.
+// The key of a slot is stored XORed with its data, so a slot torn by concurrent writes doesn't verify
+// and reads as empty.
+type table struct {
+	slots []slot
+	mask  uint64
+}
+
+type slot struct {
+	key  atomic.Uint64
+	data atomic.Uint64
+}
+
+// newTable creates a table with the given number of slots, rounded up to a power of two.
+func newTable(size int) *table {
+	n := 1
+	for n < size {
+		n <<= 1
+	}
+
+	return &table{slots: make([]slot, n), mask: uint64(n - 1)}
+}
+
+func (t *table) load(hash uint64) (entry, bool) {
+	s := &t.slots[hash&t.mask]
+	data := s.data.Load()
+	if s.key.Load()^data != hash || data == 0 {
+		return entry{}, false
+	}
+
+	return unpack(data), true
+}
+
+// store keeps the deeper result if the slot already holds the same position. Other positions are replaced.
+func (t *table) store(hash uint64, e entry) {
+	s := &t.slots[hash&t.mask]
+	if old, ok := t.load(hash); ok && old.depth > e.depth {
+		return
+	}
+
+	data := e.pack()
+	s.data.Store(data)
+	s.key.Store(hash ^ data)
+}
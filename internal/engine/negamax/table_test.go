@@ -0,0 +1,36 @@
+package engine_negamax
+
+import "testing"
+
+func TestTableKeepsDeeperResults(t *testing.T) {
+	tb := newTable(16)
+	tb.store(1, entry{score: -5, depth: 4, bound: boundLower, move: 3})
+	tb.store(1, entry{score: 7, depth: 2, bound: boundExact, move: 1})
+
+	if e, ok := tb.load(1); !ok || e != (entry{score: -5, depth: 4, bound: boundLower, move: 3}) {
+		t.Fatalf("load = %+v, %v; want the deeper result", e, ok)
+	}
+}
+
+func TestTableReplacesOtherPositions(t *testing.T) {
+	tb := newTable(16)
+	tb.store(1, entry{score: 1, depth: 9, move: 1})
+	tb.store(17, entry{score: 2, depth: 1, move: 2}) // Same slot.
+
+	if _, ok := tb.load(1); ok {
+		t.Fatal("replaced position still found")
+	}
+	if e, ok := tb.load(17); !ok || e.score != 2 {
+		t.Fatalf("load = %+v, %v; want the new position", e, ok)
+	}
+}
+
+func TestTableIgnoresTornSlots(t *testing.T) {
+	tb := newTable(16)
+	tb.store(1, entry{score: 1, depth: 1, move: 1})
+	tb.slots[1].data.Store(entry{score: 2, depth: 1, move: 2}.pack()) // Another writer got halfway.
+
+	if e, ok := tb.load(1); ok {
+		t.Fatalf("load = %+v; want the torn slot to read as empty", e)
+	}
+}
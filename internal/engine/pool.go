@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var queueWaitDurations = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name:    "connect_four_bot_engine_queue_wait_seconds",
+	Help:    "The time moves waited for a free engine worker in seconds.",
+	Buckets: prometheus.DefBuckets,
+})
+
+var computeDurations = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name:    "connect_four_bot_engine_compute_seconds",
+	Help:    "The time the engine calculated moves in seconds.",
+	Buckets: prometheus.DefBuckets,
+})
+
+var ponderDurations = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name:    "connect_four_bot_engine_ponder_seconds",
+	Help:    "The time the engine pondered on the opponent's time in seconds.",
+	Buckets: prometheus.DefBuckets,
+})
+
+var cancellationsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "connect_four_bot_engine_cancellations_total",
+	Help: "The number of calculations that were no longer needed, by whether they were still queued or computing.",
+}, []string{"stage"})
+
+var helperWorkersCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "connect_four_bot_engine_helper_workers_total",
+	Help: "The number of extra workers parallel searches asked for, by whether they were granted.",
+}, []string{"result"})
+
+var busyWorkersGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "connect_four_bot_engine_busy_workers",
+	Help: "The number of engine workers currently calculating.",
+})
+
+type PoolOptions struct {
+	// Workers is the number of calculations run at once. 0 means one per CPU.
+	Workers int
+	// PonderShare is the percentage of the workers that may ponder at once, at least one. 0 disables pondering.
+	PonderShare int
+}
+
+// Pool is the global CPU budget of the engines. It runs as many calculations at once as it has workers,
+// so that many concurrent games can't starve each other or the rest of the bot.
+type Pool struct {
+	workers chan struct{}
+	ponders chan struct{} // nil if pondering is disabled.
+}
+
+func NewPool(options PoolOptions) *Pool {
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	p := &Pool{workers: make(chan struct{}, workers)}
+	if options.PonderShare > 0 {
+		p.ponders = make(chan struct{}, max(1, workers*min(options.PonderShare, 100)/100))
+	}
+
+	return p
+}
+
+// Wrap runs the calculations of the engine on the pool. Calculations wait for a free worker in order of arrival.
+// Once ctx is done, it returns false right away. The worker stays busy until the engine gives up,
+// so the game must not be changed until then.
+func (p *Pool) Wrap(calculateNextMove CalculateNextMove) CalculateNextMove {
+	return func(ctx context.Context, game *connectfour.Game) (int, bool) {
+		if ctx.Err() != nil {
+			cancellationsCounter.With(map[string]string{"stage": "queued"}).Inc()
+			return 0, false
+		}
+
+		queuedAt := time.Now()
+		select {
+		case p.workers <- struct{}{}:
+		case <-ctx.Done():
+			cancellationsCounter.With(map[string]string{"stage": "queued"}).Inc()
+			return 0, false
+		}
+		queueWaitDurations.Observe(time.Since(queuedAt).Seconds())
+
+		type result struct {
+			column int
+			ok     bool
+		}
+		results := make(chan result, 1)
+		go func() {
+			busyWorkersGauge.Inc()
+			defer func() {
+				busyWorkersGauge.Dec()
+				<-p.workers
+			}()
+
+			startedAt := time.Now()
+			column, ok := calculateNextMove(ctx, game)
+			computeDurations.Observe(time.Since(startedAt).Seconds())
+			results <- result{column: column, ok: ok}
+		}()
+
+		select {
+		case res := <-results:
+			return res.column, res.ok
+		case <-ctx.Done():
+			cancellationsCounter.With(map[string]string{"stage": "computing"}).Inc()
+			return 0, false
+		}
+	}
+}
+
+// WrapPonder runs the pondering of the engine on the pool, within the ponder share of the workers.
+// Pondering waits for a free worker like calculations do, and gives up once ctx is done.
+func (p *Pool) WrapPonder(ponder Ponder) Ponder {
+	return func(ctx context.Context, game *connectfour.Game) {
+		if p.ponders == nil {
+			return
+		}
+
+		select {
+		case p.ponders <- struct{}{}:
+			defer func() { <-p.ponders }()
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case p.workers <- struct{}{}:
+			defer func() { <-p.workers }()
+		case <-ctx.Done():
+			return
+		}
+
+		busyWorkersGauge.Inc()
+		defer busyWorkersGauge.Dec()
+		startedAt := time.Now()
+		ponder(ctx, game)
+		ponderDurations.Observe(time.Since(startedAt).Seconds())
+	}
+}
+
+// TryAcquire implements Budget. Parallel searches only get idle workers, so they don't delay the moves of other games.
+func (p *Pool) TryAcquire(n int) (int, func()) {
+	acquired := 0
+acquire:
+	for acquired < n {
+		select {
+		case p.workers <- struct{}{}:
+			acquired++
+		default:
+			break acquire
+		}
+	}
+	busyWorkersGauge.Add(float64(acquired))
+	helperWorkersCounter.With(map[string]string{"result": "granted"}).Add(float64(acquired))
+	helperWorkersCounter.With(map[string]string{"result": "denied"}).Add(float64(n - acquired))
+
+	return acquired, func() {
+		busyWorkersGauge.Sub(float64(acquired))
+		for range acquired {
+			<-p.workers
+		}
+	}
+}
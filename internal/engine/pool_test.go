@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// blockingEngine plays column 1 once released, or gives up when ctx is done.
+func blockingEngine(started chan<- struct{}, release <-chan struct{}) CalculateNextMove {
+	return func(ctx context.Context, game *connectfour.Game) (int, bool) {
+		started <- struct{}{}
+		select {
+		case <-release:
+			return 1, true
+		case <-ctx.Done():
+			return 0, false
+		}
+	}
+}
+
+func TestPoolLimitsConcurrentCalculations(t *testing.T) {
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	calculateNextMove := NewPool(PoolOptions{Workers: 1}).Wrap(blockingEngine(started, release))
+	game := connectfour.NewGame("g", "", "", 7, 6)
+
+	first := make(chan int, 1)
+	go func() {
+		column, _ := calculateNextMove(context.Background(), game)
+		first <- column
+	}()
+	<-started
+
+	second := make(chan int, 1)
+	go func() {
+		column, _ := calculateNextMove(context.Background(), game)
+		second <- column
+	}()
+	select {
+	case <-started:
+		t.Fatal("second calculation started while the only worker is busy")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if <-first != 1 || <-second != 1 {
+		t.Fatal("calculations didn't complete after the release")
+	}
+}
+
+func TestPoolGivesUpOnQueuedCalculations(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	defer close(release)
+	pool := NewPool(PoolOptions{Workers: 1})
+	calculateNextMove := pool.Wrap(blockingEngine(started, release))
+	game := connectfour.NewGame("g", "", "", 7, 6)
+
+	go calculateNextMove(context.Background(), game)
+	<-started
+
+	before := testutil.ToFloat64(cancellationsCounter.WithLabelValues("queued"))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, ok := calculateNextMove(ctx, game); ok {
+		t.Fatal("queued calculation succeeded; want it given up")
+	}
+	if n := testutil.ToFloat64(cancellationsCounter.WithLabelValues("queued")) - before; n != 1 {
+		t.Fatalf("%v queued cancellations counted; want 1", n)
+	}
+}
+
+func TestPoolReturnsOnCancellationAndKeepsWorkerUntilEngineGivesUp(t *testing.T) {
+	started := make(chan struct{}, 2)
+	stubborn := make(chan struct{})
+	pool := NewPool(PoolOptions{Workers: 1})
+	game := connectfour.NewGame("g", "", "", 7, 6)
+	calculateNextMove := pool.Wrap(func(ctx context.Context, game *connectfour.Game) (int, bool) {
+		started <- struct{}{}
+		<-stubborn // Doesn't look at ctx.
+		return 1, true
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool, 1)
+	go func() {
+		_, ok := calculateNextMove(ctx, game)
+		done <- ok
+	}()
+	<-started
+	cancel()
+	if <-done {
+		t.Fatal("cancelled calculation succeeded; want it given up")
+	}
+
+	go calculateNextMove(context.Background(), game)
+	select {
+	case <-started:
+		t.Fatal("next calculation started while the engine of the cancelled one still runs")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(stubborn)
+	<-started
+}
+
+func TestPoolLimitsPonderingToShare(t *testing.T) {
+	started := make(chan struct{}, 2)
+	ponder := NewPool(PoolOptions{Workers: 4, PonderShare: 25}).WrapPonder(func(ctx context.Context, game *connectfour.Game) {
+		started <- struct{}{}
+		<-ctx.Done()
+	})
+	game := connectfour.NewGame("g", "", "", 7, 6)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go ponder(ctx, game)
+	go ponder(ctx, game)
+	<-started
+	select {
+	case <-started:
+		t.Fatal("second game pondered beyond the share of one worker")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancel()
+}
+
+func TestPoolDoesNotPonderWithoutShare(t *testing.T) {
+	pondered := false
+	ponder := NewPool(PoolOptions{Workers: 4}).WrapPonder(func(ctx context.Context, game *connectfour.Game) {
+		pondered = true
+	})
+
+	ponder(context.Background(), connectfour.NewGame("g", "", "", 7, 6))
+	if pondered {
+		t.Fatal("pondered; want it disabled")
+	}
+}
+
+func TestPoolLendsOnlyIdleWorkers(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	defer close(release)
+	pool := NewPool(PoolOptions{Workers: 3})
+	go pool.Wrap(blockingEngine(started, release))(context.Background(), connectfour.NewGame("g", "", "", 7, 6))
+	<-started
+
+	helpers, giveBack := pool.TryAcquire(5)
+	if helpers != 2 {
+		t.Fatalf("TryAcquire = %d; want the 2 idle workers", helpers)
+	}
+	if n, _ := pool.TryAcquire(1); n != 0 {
+		t.Fatalf("TryAcquire = %d while all workers are busy; want 0", n)
+	}
+
+	giveBack()
+	n, giveBackAgain := pool.TryAcquire(2)
+	defer giveBackAgain()
+	if n != 2 {
+		t.Fatalf("TryAcquire = %d after giving back; want 2", n)
+	}
+}
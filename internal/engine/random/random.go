@@ -1,12 +1,13 @@
 package engine_random
 
 import (
+	"context"
 	"math/rand"
 
 	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
 )
 
-func CalculateNextMove(game *connectfour.Game) (int, bool) {
+func CalculateNextMove(ctx context.Context, game *connectfour.Game) (int, bool) {
 	availableColumns := game.GetAvailableColumns()
 	if len(availableColumns) == 0 {
 		return 0, false
@@ -1,6 +1,7 @@
 package engine_random
 
 import (
+	"context"
 	"testing"
 
 	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
@@ -10,9 +11,8 @@ var iterationsPerCase = 1000
 
 func TestIgnoreFullColumns(t *testing.T) {
 	for i := 0; i < iterationsPerCase; i++ {
-		game := connectfour.NewGame("", "", "", 2, 1)
-		game.ApplyMove(1, 1)
-		x, ok := CalculateNextMove(game)
+		game, _ := connectfour.ParseMoves(2, 1, "1")
+		x, ok := CalculateNextMove(context.Background(), game)
 
 		if x == 1 || !ok {
 			t.Fatalf("Unexpected move %d, %v", x, ok)
@@ -22,9 +22,8 @@ func TestIgnoreFullColumns(t *testing.T) {
 
 func TestFull(t *testing.T) {
 	for i := 0; i < iterationsPerCase; i++ {
-		game := connectfour.NewGame("", "", "", 1, 1)
-		game.ApplyMove(1, 1)
-		x, ok := CalculateNextMove(game)
+		game, _ := connectfour.ParseMoves(1, 1, "1")
+		x, ok := CalculateNextMove(context.Background(), game)
 
 		if x != 0 && ok {
 			t.Fatalf("Unexpected move %d, %v", x, ok)
@@ -0,0 +1,207 @@
+package platformtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// historySize is how many messages the broker keeps to resume subscriptions from, like nchan_message_buffer_length.
+const historySize = 1024
+
+// broker serves SSE subscriptions like Nchan's subscriber endpoint: GET /sub?id=<channel>[,<channel>...].
+// Messages are delivered to clients subscribed at the time of publishing. A client sending Last-Event-ID
+// additionally gets the later messages of its channels.
+// Like Nchan's, the event IDs have an entry "<time>:<tag>" per channel of the subscription, with the tag of the
+// channel the message was published to in brackets, e.g. "3:0,5:[0]". The time is the number of messages
+// published to the channel, -1 in Last-Event-ID resumes the channel with the newer messages only.
+type broker struct {
+	channels  map[string]map[*subscription]struct{}
+	history   []message
+	sequence  int            // Of all messages, the platform puts it into the payload.
+	positions map[string]int // The number of messages published to each channel.
+	dropped   chan struct{}  // Closed to drop the open subscriptions.
+	done      chan struct{}
+	mutex     sync.Mutex
+}
+
+type subscription struct {
+	channels []string
+	messages chan string
+}
+
+type message struct {
+	channel   string
+	data      string
+	positions map[string]int // Of all channels, after publishing the message.
+}
+
+// format builds the message for a subscription to the channels.
+func (m message) format(channels []string) string {
+	entries := make([]string, len(channels))
+	for i, channel := range channels {
+		entries[i] = fmt.Sprintf("%d:0", m.positions[channel])
+		if channel == m.channel && len(channels) > 1 {
+			entries[i] = fmt.Sprintf("%d:[0]", m.positions[channel])
+		}
+	}
+
+	return fmt.Sprintf("id: %s\ndata: %s\n\n", strings.Join(entries, ","), m.data)
+}
+
+func newBroker() *broker {
+	return &broker{
+		channels:  make(map[string]map[*subscription]struct{}),
+		positions: make(map[string]int),
+		dropped:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (b *broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if r.URL.Path != "/sub" || id == "" {
+		http.NotFound(w, r)
+		return
+	}
+	channels := strings.Split(id, ",") // Nchan's multi-channel subscribe.
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	sub, dropped := b.subscribe(channels, resumePositions(r.Header.Get("Last-Event-ID"), channels))
+	defer b.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case data := <-sub.messages:
+			if _, err := fmt.Fprint(w, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-dropped:
+			return
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// publish formats the event like the platform: "<name>:<sequence>:<json payload>".
+func (b *broker) publish(channel string, eventName string, payload any) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		panic(err) // The payloads are built by this package.
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.sequence++
+	b.positions[channel]++
+	msg := message{
+		channel:   channel,
+		data:      fmt.Sprintf("%s:%d:%s", eventName, b.sequence, body),
+		positions: maps.Clone(b.positions),
+	}
+	b.history = append(b.history, msg)
+	if len(b.history) > historySize {
+		b.history = b.history[1:]
+	}
+
+	for sub := range b.channels[channel] {
+		send(sub, msg)
+	}
+}
+
+// send doesn't block if the subscriber doesn't keep up, like Nchan doesn't block the publisher.
+func send(sub *subscription, msg message) {
+	select {
+	case sub.messages <- msg.format(sub.channels):
+	default:
+	}
+}
+
+// resumePositions parses the Last-Event-ID of a subscription to the channels. It returns nil without a valid ID.
+func resumePositions(lastEventId string, channels []string) map[string]int {
+	entries := strings.Split(lastEventId, ",")
+	if lastEventId == "" || len(entries) != len(channels) {
+		return nil
+	}
+
+	positions := make(map[string]int)
+	for i, entry := range entries {
+		position, _, _ := strings.Cut(entry, ":")
+		var err error
+		if positions[channels[i]], err = strconv.Atoi(position); err != nil {
+			return nil
+		}
+	}
+
+	return positions
+}
+
+// subscribe queues the messages published to each channel after its position to resume from, if any.
+func (b *broker) subscribe(channels []string, resume map[string]int) (*subscription, chan struct{}) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	sub := &subscription{channels: channels, messages: make(chan string, 256)}
+	for _, channel := range channels {
+		if b.channels[channel] == nil {
+			b.channels[channel] = make(map[*subscription]struct{})
+		}
+		b.channels[channel][sub] = struct{}{}
+	}
+
+	for _, msg := range b.history {
+		if from, ok := resume[msg.channel]; ok && from >= 0 && msg.positions[msg.channel] > from {
+			send(sub, msg)
+		}
+	}
+
+	return sub, b.dropped
+}
+
+func (b *broker) unsubscribe(sub *subscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, channel := range sub.channels {
+		delete(b.channels[channel], sub)
+	}
+}
+
+func (b *broker) subscribers(channel string) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return len(b.channels[channel])
+}
+
+// drop ends the open subscriptions, as if the connections were lost.
+func (b *broker) drop() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	close(b.dropped)
+	b.dropped = make(chan struct{})
+}
+
+func (b *broker) close() {
+	close(b.done)
+}
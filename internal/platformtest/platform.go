@@ -0,0 +1,298 @@
+// Package platformtest provides an in-memory stand-in for the gaming platform, so the bot can be tested without
+// RabbitMQ and Nchan. The Platform answers the RPC messages of the connect four, chat and identity services
+// with real game rules and publishes the matching events on an Nchan compatible SSE endpoint.
+package platformtest
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	connectfourv1 "github.com/gaming-platform/api/go/connectfour/v1"
+	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
+)
+
+type GameState int
+
+const (
+	GameOpen GameState = iota
+	GameRunning
+	GameWon
+	GameDrawn
+	GameAborted
+	GameTimedOut
+	GameResigned
+)
+
+func (s GameState) String() string {
+	switch s {
+	case GameOpen:
+		return "open"
+	case GameRunning:
+		return "running"
+	case GameWon:
+		return "won"
+	case GameDrawn:
+		return "drawn"
+	case GameAborted:
+		return "aborted"
+	case GameTimedOut:
+		return "timed_out"
+	case GameResigned:
+		return "resigned"
+	default:
+		return "unknown"
+	}
+}
+
+// Game is a snapshot of a game on the platform.
+type Game struct {
+	GameId          string
+	ChatId          string
+	State           GameState
+	OpenerId        string
+	RedPlayerId     string
+	YellowPlayerId  string
+	CurrentPlayerId string // Empty if the game isn't running.
+	WinnerId        string
+	Board           *connectfour.Game
+}
+
+type game struct {
+	id             string
+	chatId         string
+	state          GameState
+	openerId       string
+	redPlayerId    string
+	yellowPlayerId string
+	winnerId       string
+	board          *connectfour.Game
+}
+
+// currentPlayerId is empty if the game isn't running.
+func (g *game) currentPlayerId() string {
+	if g.state != GameRunning {
+		return ""
+	}
+
+	if current, _ := g.board.GetCurrentPlayerColors(); current == 1 {
+		return g.redPlayerId
+	}
+
+	return g.yellowPlayerId
+}
+
+func (g *game) snapshot() Game {
+	return Game{
+		GameId:          g.id,
+		ChatId:          g.chatId,
+		State:           g.state,
+		OpenerId:        g.openerId,
+		RedPlayerId:     g.redPlayerId,
+		YellowPlayerId:  g.yellowPlayerId,
+		CurrentPlayerId: g.currentPlayerId(),
+		WinnerId:        g.winnerId,
+		Board:           g.board.Clone(),
+	}
+}
+
+func (g *game) hasPlayer(playerId string) bool {
+	return playerId != "" && (playerId == g.redPlayerId || playerId == g.yellowPlayerId)
+}
+
+func (g *game) toProto() *connectfourv1.Game {
+	moves := make([]*connectfourv1.Move, 0, g.board.MoveCount())
+	for _, mv := range g.board.Moves() {
+		moves = append(moves, &connectfourv1.Move{X: int32(mv.X), Y: int32(mv.Y), Color: int32(mv.Color)})
+	}
+
+	return &connectfourv1.Game{
+		GameId:          g.id,
+		ChatId:          g.chatId,
+		CurrentPlayerId: g.currentPlayerId(),
+		Width:           int32(g.board.Width),
+		Height:          int32(g.board.Height),
+		Moves:           moves,
+	}
+}
+
+type Platform struct {
+	games    map[string]*game
+	gameIds  []string          // In the order the games were opened.
+	bots     map[string]string // Bot ids by username.
+	messages map[string][]ChatMessage
+	nextId   int
+	mutex    sync.Mutex
+
+	broker *broker
+	server *httptest.Server
+}
+
+type ChatMessage struct {
+	AuthorId string
+	Message  string
+}
+
+// New starts the platform. Close it at the end of the test.
+func New() *Platform {
+	p := &Platform{
+		games:    make(map[string]*game),
+		bots:     make(map[string]string),
+		messages: make(map[string][]ChatMessage),
+		broker:   newBroker(),
+	}
+	p.server = httptest.NewServer(p.broker)
+
+	return p
+}
+
+func (p *Platform) Close() {
+	p.broker.close()
+	p.server.Close()
+}
+
+// NchanSubUrl is the URL to pass to sse.NewClient.
+func (p *Platform) NchanSubUrl() string {
+	return p.server.URL + "/sub"
+}
+
+// Game returns a snapshot of the game.
+func (p *Platform) Game(gameId string) (Game, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	g, ok := p.games[gameId]
+	if !ok {
+		return Game{}, false
+	}
+
+	return g.snapshot(), true
+}
+
+// Games returns snapshots of all games in the order they were opened.
+func (p *Platform) Games() []Game {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	games := make([]Game, 0, len(p.gameIds))
+	for _, id := range p.gameIds {
+		games = append(games, p.games[id].snapshot())
+	}
+
+	return games
+}
+
+// Messages returns the messages written to the chat.
+func (p *Platform) Messages(chatId string) []ChatMessage {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return append([]ChatMessage(nil), p.messages[chatId]...)
+}
+
+// Abort aborts the game like the platform does if a game doesn't start in time.
+func (p *Platform) Abort(gameId string) error {
+	return p.finish(gameId, GameAborted, "ConnectFour.GameAborted", "abortedPlayerId")
+}
+
+// TimeOut ends the game like the platform does if the current player doesn't move in time.
+func (p *Platform) TimeOut(gameId string) error {
+	return p.finish(gameId, GameTimedOut, "ConnectFour.GameTimedOut", "timedOutPlayerId")
+}
+
+// Resign ends the game like the platform does if the current player resigns.
+func (p *Platform) Resign(gameId string) error {
+	return p.finish(gameId, GameResigned, "ConnectFour.GameResigned", "resignedPlayerId")
+}
+
+// DropSubscriptions ends all open SSE subscriptions, as if the connections to Nchan were lost.
+func (p *Platform) DropSubscriptions() {
+	p.broker.drop()
+}
+
+// WaitForSubscribers blocks until at least n clients listen on the SSE channel.
+// Nchan doesn't replay messages to late subscribers, so tests wait for the bot before they publish.
+func (p *Platform) WaitForSubscribers(ctx context.Context, channel string, n int) error {
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+
+	for p.broker.subscribers(channel) < n {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for %d subscribers on %s: %w", n, channel, ctx.Err())
+		}
+	}
+
+	return nil
+}
+
+// WaitForGame blocks until the game satisfies the condition.
+func (p *Platform) WaitForGame(ctx context.Context, gameId string, cond func(Game) bool) (Game, error) {
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if g, ok := p.Game(gameId); ok && cond(g) {
+			return g, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return Game{}, fmt.Errorf("waiting for game %s: %w", gameId, ctx.Err())
+		}
+	}
+}
+
+// finish ends the game because of the current player, or the opener if the game is open.
+// The payload names that player with playerKey.
+func (p *Platform) finish(gameId string, state GameState, eventName string, playerKey string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	g, ok := p.games[gameId]
+	if !ok {
+		return fmt.Errorf("game %s not found", gameId)
+	} else if g.state != GameOpen && g.state != GameRunning {
+		return fmt.Errorf("game %s is already %s", gameId, g.state)
+	}
+
+	playerId, opponentId := g.openerId, ""
+	if g.state == GameRunning {
+		playerId = g.currentPlayerId()
+		opponentId = g.redPlayerId
+		if opponentId == playerId {
+			opponentId = g.yellowPlayerId
+		}
+	}
+	payload := map[string]any{"gameId": gameId, playerKey: playerId, "opponentPlayerId": opponentId}
+
+	if state == GameAborted {
+		p.publish(lobbyChannel, eventName, payload)
+	} else {
+		g.winnerId = opponentId
+	}
+	g.state = state
+	p.publish(gameChannel(gameId), eventName, payload)
+
+	return nil
+}
+
+func (p *Platform) newId(prefix string) string {
+	p.nextId++
+
+	return fmt.Sprintf("%s-%d", prefix, p.nextId)
+}
+
+func (p *Platform) publish(channel string, eventName string, payload any) {
+	p.broker.publish(channel, eventName, payload)
+}
+
+const lobbyChannel = "lobby"
+
+func gameChannel(gameId string) string {
+	return "connect-four-" + gameId
+}
@@ -0,0 +1,296 @@
+package platformtest
+
+import (
+	"context"
+	"fmt"
+
+	chatv1 "github.com/gaming-platform/api/go/chat/v1"
+	commonv1 "github.com/gaming-platform/api/go/common/v1"
+	connectfourv1 "github.com/gaming-platform/api/go/connectfour/v1"
+	identityv1 "github.com/gaming-platform/api/go/identity/v1"
+	"github.com/gaming-platform/connect-four-bot/internal/api"
+	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
+	"github.com/gaming-platform/connect-four-bot/internal/rpcclient"
+	"google.golang.org/protobuf/proto"
+)
+
+type rpcClient struct {
+	platform *Platform
+}
+
+// RpcClient returns a client that sends the requests to the platform.
+func (p *Platform) RpcClient() rpcclient.RpcClient {
+	return &rpcClient{platform: p}
+}
+
+func (c *rpcClient) Call(ctx context.Context, req rpcclient.Message) (rpcclient.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return rpcclient.Message{}, err
+	}
+
+	switch req.Name {
+	case connectfourv1.OpenGameType:
+		return handle(req, connectfourv1.OpenGameResponseType, c.platform.openGame)
+	case connectfourv1.JoinGameType:
+		return handle(req, connectfourv1.JoinGameResponseType, c.platform.joinGame)
+	case connectfourv1.MakeMoveType:
+		return handle(req, connectfourv1.MakeMoveResponseType, c.platform.makeMove)
+	case connectfourv1.GetOpenGamesType:
+		return handle(req, connectfourv1.GetOpenGamesResponseType, c.platform.getOpenGames)
+	case connectfourv1.GetGamesByPlayerType:
+		return handle(req, connectfourv1.GetGamesByPlayerResponseType, c.platform.getGamesByPlayer)
+	case chatv1.WriteMessageType:
+		return handle(req, chatv1.WriteMessageResponseType, c.platform.writeMessage)
+	case identityv1.RegisterBotType:
+		return handle(req, identityv1.RegisterBotResponseType, c.platform.registerBot)
+	case identityv1.GetBotByUsernameType:
+		return handle(req, identityv1.GetBotByUsernameResponseType, c.platform.getBotByUsername)
+	default:
+		return rpcclient.Message{}, fmt.Errorf("%w: %s", rpcclient.ErrUnroutable, req.Name)
+	}
+}
+
+func (c *rpcClient) Close() error {
+	return nil
+}
+
+// handle decodes the request, lets the handler process it and encodes the response or the violation.
+func handle[Req any, PReq interface {
+	*Req
+	proto.Message
+}, Resp proto.Message](
+	req rpcclient.Message,
+	respName string,
+	handler func(*Req) (Resp, api.ViolationIdentifier),
+) (rpcclient.Message, error) {
+	var decoded Req
+	if err := proto.Unmarshal(req.Body, PReq(&decoded)); err != nil {
+		return rpcclient.Message{}, err
+	}
+
+	resp, violation := handler(&decoded)
+	if violation != "" {
+		body, err := proto.Marshal(&commonv1.ErrorResponse{
+			Violations: []*commonv1.ErrorResponse_Violation{{Identifier: string(violation)}},
+		})
+
+		return rpcclient.Message{Name: commonv1.ErrorResponseType, Body: body}, err
+	}
+
+	body, err := proto.Marshal(resp)
+
+	return rpcclient.Message{Name: respName, Body: body}, err
+}
+
+// openGame makes the opener red unless yellow is requested explicitly, to keep tests deterministic.
+func (p *Platform) openGame(req *connectfourv1.OpenGame) (*connectfourv1.OpenGameResponse, api.ViolationIdentifier) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	g := &game{
+		id:       p.newId("game"),
+		state:    GameOpen,
+		openerId: req.PlayerId,
+		board:    connectfour.NewGame("", "", "", int(req.Width), int(req.Height)),
+	}
+	g.board.GameId = g.id
+	if req.Stone == connectfourv1.OpenGame_STONE_YELLOW {
+		g.yellowPlayerId = req.PlayerId
+	} else {
+		g.redPlayerId = req.PlayerId
+	}
+	p.games[g.id] = g
+	p.gameIds = append(p.gameIds, g.id)
+
+	p.publish(lobbyChannel, "ConnectFour.GameOpened", map[string]any{
+		"gameId":         g.id,
+		"playerId":       req.PlayerId,
+		"width":          req.Width,
+		"height":         req.Height,
+		"preferredStone": int32(req.Stone),
+		"timer":          req.Timer,
+	})
+
+	return &connectfourv1.OpenGameResponse{GameId: g.id}, ""
+}
+
+func (p *Platform) joinGame(req *connectfourv1.JoinGame) (*connectfourv1.JoinGameResponse, api.ViolationIdentifier) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	g, ok := p.games[req.GameId]
+	if !ok {
+		return nil, api.ViolationGameNotFound
+	} else if g.hasPlayer(req.PlayerId) {
+		return nil, api.ViolationPlayerAlreadyJoined
+	} else if g.state != GameOpen {
+		return nil, api.ViolationGameNotOpen
+	}
+
+	if g.redPlayerId == "" {
+		g.redPlayerId = req.PlayerId
+	} else {
+		g.yellowPlayerId = req.PlayerId
+	}
+	g.state = GameRunning
+	g.chatId = p.newId("chat")
+
+	joined := map[string]any{
+		"gameId":           g.id,
+		"opponentPlayerId": req.PlayerId,
+		"redPlayerId":      g.redPlayerId,
+		"yellowPlayerId":   g.yellowPlayerId,
+	}
+	p.publish(lobbyChannel, "ConnectFour.PlayerJoined", joined)
+	p.publish(gameChannel(g.id), "ConnectFour.PlayerJoined", joined)
+	p.publish(gameChannel(g.id), "ConnectFour.ChatAssigned", map[string]any{"gameId": g.id, "chatId": g.chatId})
+
+	return &connectfourv1.JoinGameResponse{GameId: g.id}, ""
+}
+
+func (p *Platform) makeMove(req *connectfourv1.MakeMove) (*connectfourv1.MakeMoveResponse, api.ViolationIdentifier) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	g, ok := p.games[req.GameId]
+	if !ok {
+		return nil, api.ViolationGameNotFound
+	} else if g.state != GameRunning {
+		return nil, api.ViolationGameNotRunning
+	} else if g.currentPlayerId() != req.PlayerId {
+		return nil, api.ViolationUnexpectedPlayer
+	}
+
+	x := int(req.Column)
+	y, ok := g.board.NextFreeRow(x)
+	if !ok {
+		return nil, api.ViolationColumnAlreadyFilled
+	}
+	color, _ := g.board.GetCurrentPlayerColors()
+	if _, err := g.board.PlayMove(x, y, color); err != nil {
+		return nil, api.ViolationColumnAlreadyFilled
+	}
+
+	switch g.board.Outcome() {
+	case connectfour.RedWon:
+		g.state, g.winnerId = GameWon, g.redPlayerId
+	case connectfour.YellowWon:
+		g.state, g.winnerId = GameWon, g.yellowPlayerId
+	case connectfour.Draw:
+		g.state = GameDrawn
+	}
+
+	p.publish(gameChannel(g.id), "ConnectFour.PlayerMoved", map[string]any{
+		"gameId":       g.id,
+		"x":            x,
+		"y":            y,
+		"color":        color,
+		"playerId":     req.PlayerId,
+		"nextPlayerId": g.currentPlayerId(),
+	})
+	switch g.state {
+	case GameWon:
+		p.publish(gameChannel(g.id), "ConnectFour.GameWon", map[string]any{"gameId": g.id, "winnerPlayerId": g.winnerId})
+	case GameDrawn:
+		p.publish(gameChannel(g.id), "ConnectFour.GameDrawn", map[string]any{"gameId": g.id})
+	}
+
+	return &connectfourv1.MakeMoveResponse{GameId: g.id}, ""
+}
+
+func (p *Platform) getOpenGames(req *connectfourv1.GetOpenGames) (*connectfourv1.GetOpenGamesResponse, api.ViolationIdentifier) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	resp := &connectfourv1.GetOpenGamesResponse{}
+	for _, id := range p.gameIds {
+		if req.Limit > 0 && len(resp.Games) >= int(req.Limit) {
+			break
+		}
+
+		if g := p.games[id]; g.state == GameOpen {
+			resp.Games = append(resp.Games, &connectfourv1.GetOpenGamesResponse_Game{
+				GameId:   g.id,
+				PlayerId: g.openerId,
+				Width:    int32(g.board.Width),
+				Height:   int32(g.board.Height),
+			})
+		}
+	}
+
+	return resp, ""
+}
+
+func (p *Platform) getGamesByPlayer(req *connectfourv1.GetGamesByPlayer) (*connectfourv1.GetGamesByPlayerResponse, api.ViolationIdentifier) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var matching []*game
+	for _, id := range p.gameIds {
+		if g := p.games[id]; g.hasPlayer(req.PlayerId) && matchesState(g, req.PlayerId, req.State) {
+			matching = append(matching, g)
+		}
+	}
+
+	page, limit := max(int(req.Page), 1), int(req.Limit)
+	if limit <= 0 {
+		limit = len(matching)
+	}
+	resp := &connectfourv1.GetGamesByPlayerResponse{}
+	for i := (page - 1) * limit; i < len(matching) && i < page*limit; i++ {
+		resp.Games = append(resp.Games, matching[i].toProto())
+	}
+
+	return resp, ""
+}
+
+func matchesState(g *game, playerId string, state connectfourv1.GetGamesByPlayer_State) bool {
+	switch state {
+	case connectfourv1.GetGamesByPlayer_STATE_OPEN:
+		return g.state == GameOpen
+	case connectfourv1.GetGamesByPlayer_STATE_RUNNING:
+		return g.state == GameRunning
+	case connectfourv1.GetGamesByPlayer_STATE_WON:
+		return g.winnerId == playerId
+	case connectfourv1.GetGamesByPlayer_STATE_LOST:
+		return g.winnerId != "" && g.winnerId != playerId
+	case connectfourv1.GetGamesByPlayer_STATE_DRAWN:
+		return g.state == GameDrawn
+	default:
+		return true
+	}
+}
+
+func (p *Platform) writeMessage(req *chatv1.WriteMessage) (*chatv1.WriteMessageResponse, api.ViolationIdentifier) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.messages[req.ChatId] = append(p.messages[req.ChatId], ChatMessage{AuthorId: req.AuthorId, Message: req.Message})
+
+	return &chatv1.WriteMessageResponse{MessageId: p.newId("message")}, ""
+}
+
+func (p *Platform) registerBot(req *identityv1.RegisterBot) (*identityv1.RegisterBotResponse, api.ViolationIdentifier) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	botId, ok := p.bots[req.Username]
+	if !ok {
+		botId = p.newId("bot")
+		p.bots[req.Username] = botId
+	}
+
+	return &identityv1.RegisterBotResponse{BotId: botId}, ""
+}
+
+func (p *Platform) getBotByUsername(req *identityv1.GetBotByUsername) (*identityv1.GetBotByUsernameResponse, api.ViolationIdentifier) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	botId, ok := p.bots[req.Username]
+	if !ok {
+		return nil, api.ViolationUsernameNotFound
+	}
+
+	return &identityv1.GetBotByUsernameResponse{Bot: &identityv1.Bot{BotId: botId, Username: req.Username}}, ""
+}
@@ -3,34 +3,215 @@ package rpcclient
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math/rand"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/rabbitmq/amqp091-go"
 )
 
+var amqpConnectedGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "app_rpc_amqp_connected",
+	Help: "Whether the RPC client is currently connected to the broker (1) or reconnecting (0).",
+})
+
+var amqpReconnectsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "app_rpc_amqp_reconnects_total",
+	Help: "The total number of attempts to reconnect to the broker.",
+}, []string{"result"})
+
+var amqpChannelReopensCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "app_rpc_amqp_channel_reopens_total",
+	Help: "The total number of attempts to reopen a pooled channel the broker closed.",
+}, []string{"result"})
+
+const (
+	reconnectMinBackoff = 500 * time.Millisecond
+	reconnectMaxBackoff = 30 * time.Second
+)
+
+// ErrUnroutable means that the broker couldn't route the request to any queue,
+// e.g. because the exchange is misconfigured or the target service isn't running.
+var ErrUnroutable = errors.New("rpc: unroutable")
+
+// ErrRejected means that the broker didn't accept the request, see AmqpOptions.PublisherConfirms.
+var ErrRejected = errors.New("rpc: rejected by broker")
+
+type AmqpOptions struct {
+	// PublisherConfirms makes each call wait until the broker has taken responsibility for the request.
+	PublisherConfirms bool
+	// Channels is the size of the channel pool the calls are spread over. 0 means 1.
+	// Each channel has its own consumer for the responses, since direct reply-to only delivers to the publishing channel.
+	Channels int
+}
+
 type amqpRpcClient struct {
-	connection           *amqp091.Connection
-	channel              *amqp091.Channel
+	dial                 func(url string) (brokerConnection, error)
+	url                  string
+	options              AmqpOptions
 	messageRouter        AmqpMessageRouter
-	responsesMessages    <-chan amqp091.Delivery
-	responseChannels     *responseChannels
+	session              *amqpSession // nil while reconnecting.
+	sessionMutex         sync.RWMutex
 	correlationIdCounter *counter
 	defaultTimeout       time.Duration
 	closeChannel         chan struct{}
 	syncClose            sync.Once
 }
 
-func NewAmqpRpcClient(url string, timeout time.Duration, msgRouter AmqpMessageRouter) (RpcClient, error) {
+// amqpSession bundles everything that has to be recreated after the connection drops.
+type amqpSession struct {
+	connection         brokerConnection
+	channels           []atomic.Pointer[amqpChannel] // An entry is nil while the channel is reopened.
+	nextChannel        atomic.Uint64
+	connectionCloseErr chan *amqp091.Error
+	closed             bool // Guarded by closeMutex, no channel is stored afterward.
+	closeMutex         sync.Mutex
+}
+
+// amqpChannel is a pooled channel with the calls waiting for responses on it.
+type amqpChannel struct {
+	channel           brokerChannel
+	responsesMessages <-chan amqp091.Delivery
+	returnedMessages  <-chan amqp091.Return
+	responseChannels  *responseChannels
+	closeErr          chan *amqp091.Error
+}
+
+// brokerConnection is the part of *amqp091.Connection the client uses, so that tests can fake the broker.
+type brokerConnection interface {
+	channel() (brokerChannel, error)
+	NotifyClose(receiver chan *amqp091.Error) chan *amqp091.Error
+	IsClosed() bool
+	Close() error
+}
+
+// brokerChannel is the part of *amqp091.Channel the client uses.
+type brokerChannel interface {
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp091.Table) (<-chan amqp091.Delivery, error)
+	Confirm(noWait bool) error
+	NotifyReturn(receiver chan amqp091.Return) chan amqp091.Return
+	NotifyClose(receiver chan *amqp091.Error) chan *amqp091.Error
+	// publish sends the message with the mandatory flag. The confirmation is only set with publisher confirms.
+	publish(ctx context.Context, exchange, key string, msg amqp091.Publishing) (brokerConfirmation, error)
+	Close() error
+}
+
+type brokerConfirmation interface {
+	WaitContext(ctx context.Context) (bool, error)
+}
+
+type amqp091Connection struct {
+	*amqp091.Connection
+}
+
+func dialAmqp091(url string) (brokerConnection, error) {
 	conn, err := amqp091.Dial(url)
 	if err != nil {
 		return nil, err
 	}
 
-	ch, err := conn.Channel()
+	return amqp091Connection{conn}, nil
+}
+
+func (c amqp091Connection) channel() (brokerChannel, error) {
+	ch, err := c.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	return amqp091Channel{ch}, nil
+}
+
+type amqp091Channel struct {
+	*amqp091.Channel
+}
+
+func (ch amqp091Channel) publish(
+	ctx context.Context,
+	exchange string,
+	key string,
+	msg amqp091.Publishing,
+) (brokerConfirmation, error) {
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, key, true, false, msg)
+	if confirmation == nil {
+		return nil, err // A nil *DeferredConfirmation in the interface wouldn't be nil.
+	}
+
+	return confirmation, err
+}
+
+// NewAmqpRpcClient fails if the broker can't be reached initially.
+// Afterward, the client reconnects with backoff whenever the connection drops, and reopens single channels that close.
+func NewAmqpRpcClient(
+	url string,
+	timeout time.Duration,
+	msgRouter AmqpMessageRouter,
+	options AmqpOptions,
+) (RpcClient, error) {
+	return newAmqpRpcClient(dialAmqp091, url, timeout, msgRouter, options)
+}
+
+func newAmqpRpcClient(
+	dial func(url string) (brokerConnection, error),
+	url string,
+	timeout time.Duration,
+	msgRouter AmqpMessageRouter,
+	options AmqpOptions,
+) (*amqpRpcClient, error) {
+	client := &amqpRpcClient{
+		dial:                 dial,
+		url:                  url,
+		options:              options,
+		messageRouter:        msgRouter,
+		correlationIdCounter: newCounter(),
+		defaultTimeout:       timeout,
+		closeChannel:         make(chan struct{}),
+	}
+
+	session, err := client.newSession()
+	if err != nil {
+		return nil, err
+	}
+	client.session = session
+	amqpConnectedGauge.Set(1)
+
+	client.superviseChannels(session)
+	go client.superviseSession(session)
+
+	return client, nil
+}
+
+func (c *amqpRpcClient) newSession() (*amqpSession, error) {
+	conn, err := c.dial(c.url)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &amqpSession{
+		connection:         conn,
+		channels:           make([]atomic.Pointer[amqpChannel], max(c.options.Channels, 1)),
+		connectionCloseErr: conn.NotifyClose(make(chan *amqp091.Error, 1)),
+	}
+	for i := range session.channels {
+		ch, err := newAmqpChannel(conn, c.options)
+		if err != nil {
+			_ = session.close()
+			return nil, err
+		}
+		session.channels[i].Store(ch)
+	}
+
+	return session, nil
+}
+
+func newAmqpChannel(conn brokerConnection, options AmqpOptions) (*amqpChannel, error) {
+	ch, err := conn.channel()
 	if err != nil {
-		_ = conn.Close()
 		return nil, err
 	}
 
@@ -45,43 +226,93 @@ func NewAmqpRpcClient(url string, timeout time.Duration, msgRouter AmqpMessageRo
 	)
 	if err != nil {
 		_ = ch.Close()
-		_ = conn.Close()
 		return nil, err
 	}
 
-	client := &amqpRpcClient{
-		connection:           conn,
-		channel:              ch,
-		messageRouter:        msgRouter,
-		responsesMessages:    respMsgs,
-		correlationIdCounter: newCounter(),
-		responseChannels:     newResponseChannels(),
-		defaultTimeout:       timeout,
-		closeChannel:         make(chan struct{}),
+	if options.PublisherConfirms {
+		if err := ch.Confirm(false); err != nil {
+			_ = ch.Close()
+			return nil, err
+		}
 	}
 
-	go client.consumeResponses()
+	pooled := &amqpChannel{
+		channel:           ch,
+		responsesMessages: respMsgs,
+		returnedMessages:  ch.NotifyReturn(make(chan amqp091.Return, 1)),
+		responseChannels:  newResponseChannels(),
+		closeErr:          ch.NotifyClose(make(chan *amqp091.Error, 1)),
+	}
+	go pooled.consumeResponses()
+	go pooled.consumeReturns()
 
-	return client, nil
+	return pooled, nil
+}
+
+func (s *amqpSession) close() error {
+	s.closeMutex.Lock()
+	s.closed = true
+	s.closeMutex.Unlock()
+
+	for i := range s.channels {
+		if ch := s.channels[i].Load(); ch != nil {
+			_ = ch.close()
+		}
+	}
+
+	if err := s.connection.Close(); err != nil && !errors.Is(err, amqp091.ErrClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// pickChannel spreads the calls round-robin over the open channels.
+func (s *amqpSession) pickChannel() *amqpChannel {
+	start := s.nextChannel.Add(1)
+	for i := range uint64(len(s.channels)) {
+		if ch := s.channels[(start+i)%uint64(len(s.channels))].Load(); ch != nil {
+			return ch
+		}
+	}
+
+	return nil
+}
+
+func (ch *amqpChannel) close() error {
+	ch.responseChannels.deleteAll()
+
+	if err := ch.channel.Close(); err != nil && !errors.Is(err, amqp091.ErrClosed) {
+		return err
+	}
+
+	return nil
 }
 
 func (c *amqpRpcClient) Call(ctx context.Context, req Message) (Message, error) {
+	session := c.currentSession()
+	if session == nil {
+		return Message{}, ErrNotConnected
+	}
+	ch := session.pickChannel()
+	if ch == nil {
+		return Message{}, ErrNotConnected
+	}
+
 	corrId := c.correlationIdCounter.nextString()
 
-	respChan := c.responseChannels.add(corrId)
-	defer c.responseChannels.delete(corrId)
+	respChan := ch.responseChannels.add(corrId)
+	defer ch.responseChannels.delete(corrId)
 
 	ctx, cancel := withTimeoutIfNone(ctx, c.defaultTimeout)
 	defer cancel()
 
 	route := c.messageRouter.Route(req)
 
-	err := c.channel.PublishWithContext(
+	confirmation, err := ch.channel.publish(
 		ctx,
 		route.exchange,
 		route.routingKey,
-		false,
-		false,
 		amqp091.Publishing{
 			CorrelationId: corrId,
 			ReplyTo:       "amq.rabbitmq.reply-to",
@@ -89,17 +320,29 @@ func (c *amqpRpcClient) Call(ctx context.Context, req Message) (Message, error)
 			Body:          req.Body,
 		},
 	)
-	if err != nil {
+	if errors.Is(err, amqp091.ErrClosed) {
+		return Message{}, fmt.Errorf("%w: %w", ErrNotConnected, err)
+	} else if err != nil {
 		return Message{}, err
 	}
 
+	if confirmation != nil { // Only set with publisher confirms.
+		if acked, err := confirmation.WaitContext(ctx); err != nil {
+			return Message{}, err
+		} else if !acked {
+			return Message{}, fmt.Errorf("%w: %s", ErrRejected, req.Name)
+		}
+	}
+
 	select {
 	case resp, ok := <-respChan:
 		if !ok {
-			return Message{}, errors.New("response channel closed")
+			return Message{}, c.sessionEndedError()
+		} else if resp.err != nil {
+			return Message{}, resp.err
 		}
 
-		return Message{Name: resp.Type, Body: resp.Body}, nil
+		return Message{Name: resp.delivery.Type, Body: resp.delivery.Body}, nil
 	case <-ctx.Done():
 		return Message{}, ctx.Err()
 	}
@@ -110,54 +353,204 @@ func (c *amqpRpcClient) Close() error {
 
 	c.syncClose.Do(func() {
 		close(c.closeChannel)
-		if err = c.channel.Close(); err != nil {
+
+		c.sessionMutex.Lock()
+		defer c.sessionMutex.Unlock()
+		if c.session != nil {
+			err = c.session.close()
+			c.session = nil
+		}
+	})
+
+	return err
+}
+
+func (c *amqpRpcClient) currentSession() *amqpSession {
+	c.sessionMutex.RLock()
+	defer c.sessionMutex.RUnlock()
+
+	return c.session
+}
+
+func (c *amqpRpcClient) sessionEndedError() error {
+	select {
+	case <-c.closeChannel:
+		return ErrClosed
+	default:
+		return ErrConnectionLost
+	}
+}
+
+func (ch *amqpChannel) consumeResponses() {
+	// The library closes the deliveries when the channel or the connection closes.
+	for resp := range ch.responsesMessages {
+		ch.responseChannels.send(resp.CorrelationId, amqpResponse{delivery: resp})
+		ch.responseChannels.delete(resp.CorrelationId)
+	}
+}
+
+// consumeReturns fails calls whose requests the broker returned because of the mandatory flag.
+func (ch *amqpChannel) consumeReturns() {
+	for ret := range ch.returnedMessages {
+		ch.responseChannels.send(ret.CorrelationId, amqpResponse{err: fmt.Errorf(
+			"%w: %s to exchange %q with routing key %q: %d %s",
+			ErrUnroutable,
+			ret.Type,
+			ret.Exchange,
+			ret.RoutingKey,
+			ret.ReplyCode,
+			ret.ReplyText,
+		)})
+		ch.responseChannels.delete(ret.CorrelationId)
+	}
+}
+
+// superviseSession replaces the session whenever the connection breaks, until the client is closed.
+func (c *amqpRpcClient) superviseSession(session *amqpSession) {
+	for {
+		select {
+		case <-session.connectionCloseErr:
+		case <-c.closeChannel:
 			return
 		}
-		if err = c.connection.Close(); err != nil {
+
+		c.sessionMutex.Lock()
+		if c.session != session { // Closed in the meantime.
+			c.sessionMutex.Unlock()
 			return
 		}
-	})
+		c.session = nil
+		c.sessionMutex.Unlock()
 
-	return err
+		amqpConnectedGauge.Set(0)
+		_ = session.close() // Fails the calls in flight, see sessionEndedError.
+
+		session = c.reconnect()
+		if session == nil {
+			return
+		}
+	}
 }
 
-func (c *amqpRpcClient) consumeResponses() {
+func (c *amqpRpcClient) superviseChannels(session *amqpSession) {
+	for i := range session.channels {
+		go c.superviseChannel(session, i)
+	}
+}
+
+// superviseChannel reopens the channel in the slot whenever the broker closes it, e.g. after a channel exception,
+// while the connection stays up. If that fails, the whole session is replaced.
+func (c *amqpRpcClient) superviseChannel(session *amqpSession, slot int) {
 	for {
+		ch := session.channels[slot].Load()
 		select {
-		case resp := <-c.responsesMessages:
-			c.responseChannels.send(resp.CorrelationId, resp)
-			c.responseChannels.delete(resp.CorrelationId)
+		case <-ch.closeErr:
+		case <-session.connectionCloseErr:
+			return // superviseSession takes over.
 		case <-c.closeChannel:
-			c.responseChannels.deleteAll()
 			return
 		}
+
+		if session.connection.IsClosed() {
+			return
+		}
+
+		session.channels[slot].Store(nil)
+		_ = ch.close() // Fails the calls in flight on this channel.
+
+		reopened, err := newAmqpChannel(session.connection, c.options)
+		if err != nil {
+			amqpChannelReopensCounter.With(map[string]string{"result": "error"}).Inc()
+			_ = session.connection.Close() // Let superviseSession reconnect.
+			return
+		}
+
+		// The session may have been closed while reopening, it wouldn't close a channel stored afterward.
+		session.closeMutex.Lock()
+		if session.closed {
+			session.closeMutex.Unlock()
+			_ = reopened.close()
+			return
+		}
+		session.channels[slot].Store(reopened)
+		session.closeMutex.Unlock()
+		amqpChannelReopensCounter.With(map[string]string{"result": "success"}).Inc()
+	}
+}
+
+// reconnect blocks until a new session is established, or returns nil if the client is closed.
+func (c *amqpRpcClient) reconnect() *amqpSession {
+	backoff := reconnectMinBackoff
+
+	for {
+		select {
+		case <-time.After(backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)))):
+		case <-c.closeChannel:
+			return nil
+		}
+
+		session, err := c.newSession()
+		if err != nil {
+			amqpReconnectsCounter.With(map[string]string{"result": "error"}).Inc()
+			backoff = min(backoff*2, reconnectMaxBackoff)
+			continue
+		}
+
+		c.sessionMutex.Lock()
+		select {
+		case <-c.closeChannel:
+			c.sessionMutex.Unlock()
+			_ = session.close()
+			return nil
+		default:
+		}
+		c.session = session
+		c.sessionMutex.Unlock()
+
+		amqpReconnectsCounter.With(map[string]string{"result": "success"}).Inc()
+		amqpConnectedGauge.Set(1)
+		c.superviseChannels(session)
+
+		return session
 	}
 }
 
+// amqpResponse is either the response or the reason why there won't be one.
+type amqpResponse struct {
+	delivery amqp091.Delivery
+	err      error
+}
+
 type responseChannels struct {
-	responses map[string]chan amqp091.Delivery
+	responses map[string]chan amqpResponse
+	closed    bool
 	mutex     sync.Mutex
 }
 
 func newResponseChannels() *responseChannels {
 	return &responseChannels{
-		responses: make(map[string]chan amqp091.Delivery),
+		responses: make(map[string]chan amqpResponse),
 	}
 }
 
-func (rc *responseChannels) add(corrId string) chan amqp091.Delivery {
+// add returns an already closed channel once deleteAll has been called, so that late callers don't wait in vain.
+func (rc *responseChannels) add(corrId string) chan amqpResponse {
 	rc.mutex.Lock()
 	defer rc.mutex.Unlock()
-	respChan := make(chan amqp091.Delivery, 1)
+	respChan := make(chan amqpResponse, 1)
+	if rc.closed {
+		close(respChan)
+		return respChan
+	}
 	rc.responses[corrId] = respChan
 	return respChan
 }
 
-func (rc *responseChannels) send(corrId string, delivery amqp091.Delivery) {
+func (rc *responseChannels) send(corrId string, resp amqpResponse) {
 	rc.mutex.Lock()
 	defer rc.mutex.Unlock()
 	if respChan, ok := rc.responses[corrId]; ok {
-		respChan <- delivery
+		respChan <- resp
 	}
 }
 
@@ -177,7 +570,8 @@ func (rc *responseChannels) deleteAll() {
 	for _, respChan := range rc.responses {
 		close(respChan)
 	}
-	rc.responses = make(map[string]chan amqp091.Delivery)
+	rc.responses = make(map[string]chan amqpResponse)
+	rc.closed = true
 }
 
 type AmqpMessageRouter interface {
@@ -219,6 +613,11 @@ func NewRouteMessagesToTransientRpcQueue(exchange, transientPartName string) *Ro
 func (r *RouteMessagesToTransientRpcQueue) Route(msg Message) Route {
 	return Route{
 		exchange:   r.exchange,
-		routingKey: strings.Split(msg.Name, ".")[0] + "." + r.transientPartName,
+		routingKey: serviceName(msg) + "." + r.transientPartName,
 	}
 }
+
+// serviceName extracts the target service from the message name, e.g. "ConnectFour" from "ConnectFour.MakeMove".
+func serviceName(msg Message) string {
+	return strings.Split(msg.Name, ".")[0]
+}
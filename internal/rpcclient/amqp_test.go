@@ -0,0 +1,584 @@
+package rpcclient
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestResponseChannelsSend(t *testing.T) {
+	rc := newResponseChannels()
+	respChan := rc.add("1")
+
+	rc.send("1", amqpResponse{delivery: amqp091.Delivery{CorrelationId: "1", Type: "Test.Response"}})
+	rc.send("2", amqpResponse{delivery: amqp091.Delivery{CorrelationId: "2"}}) // Unknown ids are dropped.
+
+	if resp := <-respChan; resp.delivery.Type != "Test.Response" {
+		t.Fatalf("resp.delivery.Type = %q; want \"Test.Response\"", resp.delivery.Type)
+	}
+}
+
+func TestResponseChannelsDeleteAllFailsPendingAndLateCalls(t *testing.T) {
+	rc := newResponseChannels()
+	pending := rc.add("1")
+
+	rc.deleteAll()
+
+	if _, ok := <-pending; ok {
+		t.Fatal("pending channel is open; want closed")
+	}
+	if _, ok := <-rc.add("2"); ok {
+		t.Fatal("channel added after deleteAll is open; want closed")
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	if !IsRetryable(ErrNotConnected) || !IsRetryable(ErrConnectionLost) {
+		t.Fatal("IsRetryable = false for transport errors; want true")
+	}
+	if IsRetryable(ErrClosed) {
+		t.Fatal("IsRetryable(ErrClosed) = true; want false")
+	}
+}
+
+func TestResponseChannelsSendError(t *testing.T) {
+	rc := newResponseChannels()
+	respChan := rc.add("1")
+
+	rc.send("1", amqpResponse{err: ErrUnroutable})
+
+	if resp := <-respChan; resp.err != ErrUnroutable {
+		t.Fatalf("resp.err = %v; want ErrUnroutable", resp.err)
+	}
+}
+
+func TestPickChannelSkipsReopeningChannels(t *testing.T) {
+	session := &amqpSession{channels: make([]atomic.Pointer[amqpChannel], 3)}
+	if session.pickChannel() != nil {
+		t.Fatal("pickChannel without open channels != nil")
+	}
+
+	first, second := &amqpChannel{}, &amqpChannel{}
+	session.channels[0].Store(first)
+	session.channels[2].Store(second)
+
+	picked := map[*amqpChannel]int{}
+	for range 6 {
+		picked[session.pickChannel()]++
+	}
+	if picked[nil] != 0 || picked[first] == 0 || picked[second] == 0 {
+		t.Fatalf("picked %v; want only and both open channels", picked)
+	}
+}
+
+// fakeAmqpBroker hands out fake connections, whose channels answer the requests with respond.
+type fakeAmqpBroker struct {
+	connections []*fakeAmqpConnection
+	respond     func(ch *fakeAmqpChannel, msg amqp091.Publishing)
+	openChannel func() error // Runs after a channel opened, before it's returned. An error fails the open.
+	nack        bool
+	mutex       sync.Mutex
+}
+
+type fakeAmqpConnection struct {
+	broker      *fakeAmqpBroker
+	channels    []*fakeAmqpChannel
+	closeNotify []chan *amqp091.Error
+	closed      bool
+	mutex       sync.Mutex
+}
+
+type fakeAmqpChannel struct {
+	broker      *fakeAmqpBroker
+	deliveries  chan amqp091.Delivery
+	returns     []chan amqp091.Return
+	closeNotify []chan *amqp091.Error
+	confirming  bool
+	closed      bool
+	closeCalls  int
+	published   int
+	mutex       sync.Mutex
+}
+
+type fakeConfirmation bool
+
+func (c fakeConfirmation) WaitContext(context.Context) (bool, error) {
+	return bool(c), nil
+}
+
+func newFakeAmqpBroker() *fakeAmqpBroker {
+	return &fakeAmqpBroker{respond: echo}
+}
+
+func echo(ch *fakeAmqpChannel, msg amqp091.Publishing) {
+	ch.deliver(amqp091.Delivery{CorrelationId: msg.CorrelationId, Type: msg.Type, Body: msg.Body})
+}
+
+func (b *fakeAmqpBroker) dial(string) (brokerConnection, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	conn := &fakeAmqpConnection{broker: b}
+	b.connections = append(b.connections, conn)
+
+	return conn, nil
+}
+
+func (b *fakeAmqpBroker) setRespond(respond func(ch *fakeAmqpChannel, msg amqp091.Publishing)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.respond = respond
+}
+
+func (b *fakeAmqpBroker) setNack(nack bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.nack = nack
+}
+
+func (b *fakeAmqpBroker) setOpenChannel(openChannel func() error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.openChannel = openChannel
+}
+
+func (b *fakeAmqpBroker) connection(t *testing.T, i int) *fakeAmqpConnection {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		b.mutex.Lock()
+		n := len(b.connections)
+		b.mutex.Unlock()
+		if n > i {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("connections = %d; want %d", n, i+1)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.connections[i]
+}
+
+func (c *fakeAmqpConnection) channel() (brokerChannel, error) {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil, amqp091.ErrClosed
+	}
+	ch := &fakeAmqpChannel{broker: c.broker, deliveries: make(chan amqp091.Delivery, 64)}
+	c.channels = append(c.channels, ch)
+	c.mutex.Unlock()
+
+	c.broker.mutex.Lock()
+	openChannel := c.broker.openChannel
+	c.broker.mutex.Unlock()
+	if openChannel != nil {
+		if err := openChannel(); err != nil {
+			ch.shutdown(nil)
+			return nil, err
+		}
+	}
+
+	return ch, nil
+}
+
+func (c *fakeAmqpConnection) NotifyClose(receiver chan *amqp091.Error) chan *amqp091.Error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.closeNotify = append(c.closeNotify, receiver)
+
+	return receiver
+}
+
+func (c *fakeAmqpConnection) IsClosed() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.closed
+}
+
+func (c *fakeAmqpConnection) Close() error {
+	if !c.shutdown(nil) {
+		return amqp091.ErrClosed
+	}
+
+	return nil
+}
+
+// shutdown closes the connection and its channels like the library does, err is nil for a graceful close.
+func (c *fakeAmqpConnection) shutdown(err *amqp091.Error) bool {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return false
+	}
+	c.closed = true
+	for _, receiver := range c.closeNotify {
+		if err != nil {
+			receiver <- err
+		}
+		close(receiver)
+	}
+	channels := slices.Clone(c.channels)
+	c.mutex.Unlock()
+
+	for _, ch := range channels {
+		ch.shutdown(err)
+	}
+
+	return true
+}
+
+// opened waits until the connection opened more than i channels and returns the i-th.
+func (c *fakeAmqpConnection) opened(t *testing.T, i int) *fakeAmqpChannel {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c.mutex.Lock()
+		n := len(c.channels)
+		c.mutex.Unlock()
+		if n > i {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("channels = %d; want %d", n, i+1)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.channels[i]
+}
+
+func (ch *fakeAmqpChannel) Consume(string, string, bool, bool, bool, bool, amqp091.Table) (<-chan amqp091.Delivery, error) {
+	return ch.deliveries, nil
+}
+
+func (ch *fakeAmqpChannel) Confirm(bool) error {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	ch.confirming = true
+
+	return nil
+}
+
+func (ch *fakeAmqpChannel) NotifyReturn(receiver chan amqp091.Return) chan amqp091.Return {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	ch.returns = append(ch.returns, receiver)
+
+	return receiver
+}
+
+func (ch *fakeAmqpChannel) NotifyClose(receiver chan *amqp091.Error) chan *amqp091.Error {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	ch.closeNotify = append(ch.closeNotify, receiver)
+
+	return receiver
+}
+
+func (ch *fakeAmqpChannel) publish(
+	_ context.Context,
+	_ string,
+	_ string,
+	msg amqp091.Publishing,
+) (brokerConfirmation, error) {
+	ch.mutex.Lock()
+	if ch.closed {
+		ch.mutex.Unlock()
+		return nil, amqp091.ErrClosed
+	}
+	ch.published++
+	confirming := ch.confirming
+	ch.mutex.Unlock()
+
+	ch.broker.mutex.Lock()
+	respond, nack := ch.broker.respond, ch.broker.nack
+	ch.broker.mutex.Unlock()
+	if !confirming {
+		go respond(ch, msg)
+		return nil, nil
+	} else if nack {
+		return fakeConfirmation(false), nil
+	}
+	go respond(ch, msg)
+
+	return fakeConfirmation(true), nil
+}
+
+func (ch *fakeAmqpChannel) Close() error {
+	ch.mutex.Lock()
+	ch.closeCalls++
+	ch.mutex.Unlock()
+
+	if !ch.shutdown(nil) {
+		return amqp091.ErrClosed
+	}
+
+	return nil
+}
+
+// shutdown closes the channel like the library does, err is nil for a graceful close.
+func (ch *fakeAmqpChannel) shutdown(err *amqp091.Error) bool {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+
+	if ch.closed {
+		return false
+	}
+	ch.closed = true
+	for _, receiver := range ch.closeNotify {
+		if err != nil {
+			receiver <- err
+		}
+		close(receiver)
+	}
+	for _, receiver := range ch.returns {
+		close(receiver)
+	}
+	close(ch.deliveries)
+
+	return true
+}
+
+func (ch *fakeAmqpChannel) deliver(delivery amqp091.Delivery) {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+
+	if !ch.closed {
+		ch.deliveries <- delivery
+	}
+}
+
+func (ch *fakeAmqpChannel) returnMessage(ret amqp091.Return) {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+
+	if !ch.closed {
+		for _, receiver := range ch.returns {
+			receiver <- ret
+		}
+	}
+}
+
+func (ch *fakeAmqpChannel) stats() (published int, closeCalls int) {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+
+	return ch.published, ch.closeCalls
+}
+
+func newTestAmqpClient(t *testing.T, broker *fakeAmqpBroker, options AmqpOptions) *amqpRpcClient {
+	t.Helper()
+
+	client, err := newAmqpRpcClient(broker.dial, "amqp://broker", 5*time.Second, NewRouteMessagesToExchange(""), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client
+}
+
+// hang makes the broker swallow the requests and returns the channels they were published on.
+func hang(broker *fakeAmqpBroker) chan *fakeAmqpChannel {
+	published := make(chan *fakeAmqpChannel, 16)
+	broker.setRespond(func(ch *fakeAmqpChannel, _ amqp091.Publishing) { published <- ch })
+
+	return published
+}
+
+func callAsync(client RpcClient) chan error {
+	errs := make(chan error, 1)
+	go func() {
+		_, err := client.Call(context.Background(), Message{Name: "Test.Request"})
+		errs <- err
+	}()
+
+	return errs
+}
+
+func TestAmqpRpcClientCalls(t *testing.T) {
+	client := newTestAmqpClient(t, newFakeAmqpBroker(), AmqpOptions{Channels: 2})
+
+	for range 4 {
+		resp, err := client.Call(context.Background(), Message{Name: "Test.Request", Body: []byte("ping")})
+		if err != nil || resp.Name != "Test.Request" || string(resp.Body) != "ping" {
+			t.Fatalf("Call = %v, %v; want the echo", resp, err)
+		}
+	}
+}
+
+func TestAmqpRpcClientFailsReturnedCalls(t *testing.T) {
+	broker := newFakeAmqpBroker()
+	broker.setRespond(func(ch *fakeAmqpChannel, msg amqp091.Publishing) {
+		ch.returnMessage(amqp091.Return{ReplyCode: 312, ReplyText: "NO_ROUTE", CorrelationId: msg.CorrelationId})
+	})
+	client := newTestAmqpClient(t, broker, AmqpOptions{})
+
+	if _, err := client.Call(context.Background(), Message{Name: "Test.Request"}); !errors.Is(err, ErrUnroutable) {
+		t.Fatalf("Call error = %v; want ErrUnroutable", err)
+	}
+}
+
+func TestAmqpRpcClientWaitsForPublisherConfirms(t *testing.T) {
+	broker := newFakeAmqpBroker()
+	client := newTestAmqpClient(t, broker, AmqpOptions{PublisherConfirms: true})
+
+	if _, err := client.Call(context.Background(), Message{Name: "Test.Request"}); err != nil {
+		t.Fatalf("Call error = %v; want nil", err)
+	}
+
+	broker.setNack(true)
+	if _, err := client.Call(context.Background(), Message{Name: "Test.Request"}); !errors.Is(err, ErrRejected) {
+		t.Fatalf("Call error = %v; want ErrRejected", err)
+	}
+}
+
+func TestAmqpRpcClientReopensClosedChannels(t *testing.T) {
+	broker := newFakeAmqpBroker()
+	client := newTestAmqpClient(t, broker, AmqpOptions{Channels: 2})
+	conn := broker.connection(t, 0)
+
+	published := hang(broker)
+	inFlight := callAsync(client)
+	broken := <-published
+
+	reopen := make(chan struct{})
+	broker.setOpenChannel(func() error { <-reopen; return nil })
+	broken.shutdown(&amqp091.Error{Code: amqp091.PreconditionFailed, Reason: "PRECONDITION_FAILED"})
+	if err := <-inFlight; !errors.Is(err, ErrConnectionLost) {
+		t.Fatalf("in-flight Call error = %v; want ErrConnectionLost", err)
+	}
+
+	// The calls fail over to the other channel while the broken one is reopened.
+	broker.setRespond(echo)
+	for range 4 {
+		if _, err := client.Call(context.Background(), Message{Name: "Test.Request"}); err != nil {
+			t.Fatalf("Call during reopen error = %v; want nil", err)
+		}
+	}
+
+	close(reopen)
+	reopened := conn.opened(t, 2)
+	deadline := time.Now().Add(5 * time.Second)
+	for published, _ := reopened.stats(); published == 0; published, _ = reopened.stats() {
+		if time.Now().After(deadline) {
+			t.Fatal("reopened channel isn't used")
+		}
+		if _, err := client.Call(context.Background(), Message{Name: "Test.Request"}); err != nil {
+			t.Fatalf("Call after reopen error = %v; want nil", err)
+		}
+	}
+	if conn.IsClosed() {
+		t.Fatal("connection closed; want only the channel reopened")
+	}
+}
+
+func TestAmqpRpcClientReconnectsWhenReopeningFails(t *testing.T) {
+	broker := newFakeAmqpBroker()
+	client := newTestAmqpClient(t, broker, AmqpOptions{})
+	first := broker.connection(t, 0)
+
+	var failed atomic.Bool
+	broker.setOpenChannel(func() error {
+		if failed.CompareAndSwap(false, true) {
+			return errors.New("channel limit reached")
+		}
+		return nil
+	})
+	first.opened(t, 0).shutdown(&amqp091.Error{Code: amqp091.ChannelError, Reason: "CHANNEL_ERROR"})
+
+	broker.connection(t, 1)
+	if !first.IsClosed() {
+		t.Fatal("first connection open; want closed to reconnect")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := client.Call(context.Background(), Message{Name: "Test.Request"})
+		if err == nil {
+			break
+		} else if !errors.Is(err, ErrNotConnected) || time.Now().After(deadline) {
+			t.Fatalf("Call error = %v; want ErrNotConnected until reconnected", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAmqpRpcClientReconnectsAfterConnectionLoss(t *testing.T) {
+	broker := newFakeAmqpBroker()
+	client := newTestAmqpClient(t, broker, AmqpOptions{Channels: 2})
+
+	published := hang(broker)
+	inFlight := callAsync(client)
+	<-published
+	broker.connection(t, 0).shutdown(&amqp091.Error{Code: amqp091.ConnectionForced, Reason: "CONNECTION_FORCED"})
+
+	if err := <-inFlight; !errors.Is(err, ErrConnectionLost) {
+		t.Fatalf("in-flight Call error = %v; want ErrConnectionLost", err)
+	}
+	if _, err := client.Call(context.Background(), Message{Name: "Test.Request"}); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("Call while reconnecting error = %v; want ErrNotConnected", err)
+	}
+
+	broker.setRespond(echo)
+	second := broker.connection(t, 1)
+	second.opened(t, 1)
+	deadline := time.Now().Add(5 * time.Second)
+	for client.currentSession() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("session not replaced")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := client.Call(context.Background(), Message{Name: "Test.Request"}); err != nil {
+		t.Fatalf("Call after reconnect error = %v; want nil", err)
+	}
+}
+
+func TestAmqpRpcClientClosesChannelReopenedDuringClose(t *testing.T) {
+	broker := newFakeAmqpBroker()
+	client := newTestAmqpClient(t, broker, AmqpOptions{})
+	conn := broker.connection(t, 0)
+	session := client.currentSession()
+
+	reopening, reopen := make(chan struct{}), make(chan struct{})
+	broker.setOpenChannel(func() error {
+		close(reopening)
+		<-reopen
+		return nil
+	})
+	conn.opened(t, 0).shutdown(&amqp091.Error{Code: amqp091.PreconditionFailed, Reason: "PRECONDITION_FAILED"})
+	<-reopening
+
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	close(reopen)
+
+	reopened := conn.opened(t, 1)
+	deadline := time.Now().Add(5 * time.Second)
+	for _, closeCalls := reopened.stats(); closeCalls == 0; _, closeCalls = reopened.stats() {
+		if time.Now().After(deadline) {
+			t.Fatal("channel reopened during close isn't closed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if session.channels[0].Load() != nil {
+		t.Fatal("channel reopened during close is pooled")
+	}
+}
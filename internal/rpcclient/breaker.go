@@ -0,0 +1,249 @@
+package rpcclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var circuitBreakerStateGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "app_rpc_circuit_breaker_state",
+	Help: "The circuit breaker state per service: 0 (closed), 1 (half-open) or 2 (open).",
+}, []string{"service"})
+
+var bulkheadInFlightGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "app_rpc_bulkhead_in_flight",
+	Help: "The number of RPC calls currently in flight per service.",
+}, []string{"service"})
+
+// ErrCircuitOpen means that the request wasn't sent because the target service recently kept failing.
+var ErrCircuitOpen = errors.New("rpc: circuit open")
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitHalfOpen
+	CircuitOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+func (s CircuitState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures that opens the circuit, see isServiceFailure.
+	FailureThreshold int
+	// OpenDuration is how long calls fail fast before a single trial call is let through.
+	OpenDuration time.Duration
+	// MaxConcurrency limits the calls in flight per service. Further calls wait for a free slot. 0 means unlimited.
+	MaxConcurrency int
+}
+
+// CircuitBreakerClient keeps a circuit breaker and a bulkhead per target service,
+// where the service is the prefix of the message name, e.g. "ConnectFour" for "ConnectFour.MakeMove".
+type CircuitBreakerClient struct {
+	rpcClient RpcClient
+	options   CircuitBreakerOptions
+	services  map[string]*serviceGuard
+	mutex     sync.Mutex
+}
+
+type serviceGuard struct {
+	name                string
+	state               CircuitState
+	consecutiveFailures int
+	openedAt            time.Time
+	trialInFlight       bool
+	slots               chan struct{} // nil if unlimited.
+	mutex               sync.Mutex
+}
+
+func NewCircuitBreakerClient(rpcClient RpcClient, options CircuitBreakerOptions) *CircuitBreakerClient {
+	return &CircuitBreakerClient{
+		rpcClient: rpcClient,
+		options:   options,
+		services:  make(map[string]*serviceGuard),
+	}
+}
+
+func (c *CircuitBreakerClient) Call(ctx context.Context, req Message) (Message, error) {
+	guard := c.guard(serviceName(req))
+
+	isTrial, err := guard.allow(c.options.OpenDuration)
+	if err != nil {
+		return Message{}, err
+	}
+
+	if err := guard.acquire(ctx); err != nil {
+		guard.cancelTrial(isTrial)
+		return Message{}, err
+	}
+	resp, err := c.rpcClient.Call(ctx, req)
+	guard.release()
+
+	guard.record(isTrial, isServiceFailure(ctx, err), c.options.FailureThreshold)
+
+	return resp, err
+}
+
+func (c *CircuitBreakerClient) Close() error {
+	return c.rpcClient.Close()
+}
+
+// States returns the circuit state of every service called so far.
+func (c *CircuitBreakerClient) States() map[string]CircuitState {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	states := make(map[string]CircuitState, len(c.services))
+	for name, guard := range c.services {
+		states[name] = guard.currentState(c.options.OpenDuration)
+	}
+
+	return states
+}
+
+func (c *CircuitBreakerClient) guard(service string) *serviceGuard {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	guard, ok := c.services[service]
+	if !ok {
+		guard = &serviceGuard{name: service}
+		if c.options.MaxConcurrency > 0 {
+			guard.slots = make(chan struct{}, c.options.MaxConcurrency)
+		}
+		c.services[service] = guard
+		circuitBreakerStateGauge.With(map[string]string{"service": service}).Set(float64(CircuitClosed))
+	}
+
+	return guard
+}
+
+// allow decides if a call may pass. It returns true if the call is the trial call of a half-open circuit.
+func (g *serviceGuard) allow(openDuration time.Duration) (bool, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	switch g.currentStateLocked(openDuration) {
+	case CircuitOpen:
+		return false, fmt.Errorf("%w for %s", ErrCircuitOpen, g.name)
+	case CircuitHalfOpen:
+		if g.trialInFlight {
+			return false, fmt.Errorf("%w for %s", ErrCircuitOpen, g.name)
+		}
+		g.trialInFlight = true
+		g.setState(CircuitHalfOpen)
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// isServiceFailure reports whether the call failed because the service is unavailable: it timed out or
+// failed in a retryable way. Any response, even an error response, shows that the service is available.
+// Calls whose context ended don't count, the caller gave up rather than the service, unless the context
+// was the one of a single attempt, see RetryOptions.AttemptTimeout.
+func isServiceFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil && !errors.Is(context.Cause(ctx), errAttemptTimedOut) {
+		return false
+	}
+
+	return errors.Is(err, context.DeadlineExceeded) || IsRetryable(err)
+}
+
+// record updates the circuit with the result of a call.
+func (g *serviceGuard) record(isTrial bool, failed bool, threshold int) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if isTrial {
+		g.trialInFlight = false
+	}
+
+	if !failed {
+		g.consecutiveFailures = 0
+		if isTrial || g.state == CircuitClosed {
+			g.setState(CircuitClosed)
+		}
+		return
+	}
+
+	g.consecutiveFailures++
+	if isTrial || (g.state == CircuitClosed && threshold > 0 && g.consecutiveFailures >= threshold) {
+		g.openedAt = time.Now()
+		g.setState(CircuitOpen)
+	}
+}
+
+// cancelTrial lets another call be the trial call if the given call never reached the service.
+func (g *serviceGuard) cancelTrial(isTrial bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if isTrial {
+		g.trialInFlight = false
+	}
+}
+
+func (g *serviceGuard) currentState(openDuration time.Duration) CircuitState {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.currentStateLocked(openDuration)
+}
+
+// currentStateLocked accounts for open circuits that became half-open by the passage of time.
+func (g *serviceGuard) currentStateLocked(openDuration time.Duration) CircuitState {
+	if g.state == CircuitOpen && time.Since(g.openedAt) >= openDuration {
+		return CircuitHalfOpen
+	}
+
+	return g.state
+}
+
+func (g *serviceGuard) setState(state CircuitState) {
+	g.state = state
+	circuitBreakerStateGauge.With(map[string]string{"service": g.name}).Set(float64(state))
+}
+
+func (g *serviceGuard) acquire(ctx context.Context) error {
+	if g.slots != nil {
+		select {
+		case g.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	bulkheadInFlightGauge.With(map[string]string{"service": g.name}).Inc()
+
+	return nil
+}
+
+func (g *serviceGuard) release() {
+	bulkheadInFlightGauge.With(map[string]string{"service": g.name}).Dec()
+
+	if g.slots != nil {
+		<-g.slots
+	}
+}
@@ -0,0 +1,207 @@
+package rpcclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAfterConsecutiveTimeouts(t *testing.T) {
+	calls := 0
+	client := NewCircuitBreakerClient(
+		funcClient(func(ctx context.Context, req Message) (Message, error) {
+			calls++
+			return Message{}, context.DeadlineExceeded
+		}),
+		CircuitBreakerOptions{FailureThreshold: 2, OpenDuration: time.Hour},
+	)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Call(context.Background(), Message{Name: "ConnectFour.MakeMove"}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("call %d error = %v; want DeadlineExceeded", i+1, err)
+		}
+	}
+
+	if _, err := client.Call(context.Background(), Message{Name: "ConnectFour.GetOpenGames"}); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Call error = %v; want ErrCircuitOpen", err)
+	}
+	if calls != 2 {
+		t.Fatalf("calls = %d; want 2", calls)
+	}
+	if _, err := client.Call(context.Background(), Message{Name: "Chat.WriteMessage"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("other service: Call error = %v; want DeadlineExceeded", err)
+	}
+
+	states := client.States()
+	if states["ConnectFour"] != CircuitOpen || states["Chat"] != CircuitClosed {
+		t.Fatalf("States = %v; want ConnectFour open and Chat closed", states)
+	}
+}
+
+func TestCircuitBreakerIgnoresOtherErrors(t *testing.T) {
+	errBadRequest := errors.New("rpc: gateway: 400 Bad Request")
+	client := NewCircuitBreakerClient(
+		funcClient(func(ctx context.Context, req Message) (Message, error) {
+			return Message{}, errBadRequest
+		}),
+		CircuitBreakerOptions{FailureThreshold: 1, OpenDuration: time.Hour},
+	)
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Call(context.Background(), Message{Name: "ConnectFour.MakeMove"}); !errors.Is(err, errBadRequest) {
+			t.Fatalf("call %d error = %v; want the error response", i+1, err)
+		}
+	}
+}
+
+func TestCircuitBreakerOpensWhenUnavailable(t *testing.T) {
+	for name, err := range map[string]error{
+		"Unavailable":    fmt.Errorf("%w: %w: gateway: 503 Service Unavailable", ErrNotConnected, ErrUnavailable),
+		"ConnectionLost": ErrConnectionLost,
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := NewCircuitBreakerClient(
+				funcClient(func(ctx context.Context, req Message) (Message, error) {
+					return Message{}, err
+				}),
+				CircuitBreakerOptions{FailureThreshold: 1, OpenDuration: time.Hour},
+			)
+
+			_, _ = client.Call(context.Background(), Message{Name: "ConnectFour.MakeMove"})
+			if state := client.States()["ConnectFour"]; state != CircuitOpen {
+				t.Fatalf("state = %v; want open", state)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerIgnoresCallerDeadline(t *testing.T) {
+	client := NewCircuitBreakerClient(
+		funcClient(func(ctx context.Context, req Message) (Message, error) {
+			<-ctx.Done()
+			return Message{}, ctx.Err()
+		}),
+		CircuitBreakerOptions{FailureThreshold: 1, OpenDuration: time.Hour},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := client.Call(ctx, Message{Name: "ConnectFour.MakeMove"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Call error = %v; want DeadlineExceeded", err)
+	}
+	if state := client.States()["ConnectFour"]; state != CircuitClosed {
+		t.Fatalf("state = %v; want closed", state)
+	}
+
+	// The timeout of a single attempt is the service's.
+	retryClient := NewRetryClient(client, RetryOptions{AttemptTimeout: time.Millisecond, DefaultPolicy: RetryPolicy{MaxAttempts: 1}})
+	if _, err := retryClient.Call(context.Background(), Message{Name: "ConnectFour.MakeMove"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("attempt: Call error = %v; want DeadlineExceeded", err)
+	}
+	if state := client.States()["ConnectFour"]; state != CircuitOpen {
+		t.Fatalf("attempt: state = %v; want open", state)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	trialStarted := make(chan struct{})
+	release := make(chan struct{})
+	client := NewCircuitBreakerClient(
+		funcClient(func(ctx context.Context, req Message) (Message, error) {
+			if fail.Load() {
+				return Message{}, context.DeadlineExceeded
+			}
+			close(trialStarted)
+			<-release
+			return Message{Name: "ConnectFour.MakeMoveResponse"}, nil
+		}),
+		CircuitBreakerOptions{FailureThreshold: 1, OpenDuration: 10 * time.Millisecond},
+	)
+	req := Message{Name: "ConnectFour.MakeMove"}
+
+	_, _ = client.Call(context.Background(), req)
+	time.Sleep(20 * time.Millisecond)
+	if state := client.States()["ConnectFour"]; state != CircuitHalfOpen {
+		t.Fatalf("state = %v; want half-open", state)
+	}
+
+	fail.Store(false)
+	trialDone := make(chan error)
+	go func() {
+		_, err := client.Call(context.Background(), req)
+		trialDone <- err
+	}()
+
+	<-trialStarted
+	if _, err := client.Call(context.Background(), req); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("concurrent call error = %v; want ErrCircuitOpen", err)
+	}
+
+	close(release)
+	if err := <-trialDone; err != nil {
+		t.Fatalf("trial call error = %v", err)
+	}
+	if state := client.States()["ConnectFour"]; state != CircuitClosed {
+		t.Fatalf("state = %v; want closed", state)
+	}
+}
+
+func TestBulkheadLimitsConcurrency(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int64
+	client := NewCircuitBreakerClient(
+		funcClient(func(ctx context.Context, req Message) (Message, error) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				m := maxInFlight.Load()
+				if n <= m || maxInFlight.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			return Message{}, nil
+		}),
+		CircuitBreakerOptions{MaxConcurrency: 2},
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = client.Call(context.Background(), Message{Name: "ConnectFour.MakeMove"})
+		}()
+	}
+	wg.Wait()
+
+	if got := maxInFlight.Load(); got != 2 {
+		t.Fatalf("max in flight = %d; want 2", got)
+	}
+}
+
+func TestBulkheadRespectsContext(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	client := NewCircuitBreakerClient(
+		funcClient(func(ctx context.Context, req Message) (Message, error) {
+			<-block
+			return Message{}, nil
+		}),
+		CircuitBreakerOptions{MaxConcurrency: 1},
+	)
+
+	go func() { _, _ = client.Call(context.Background(), Message{Name: "ConnectFour.MakeMove"}) }()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.Call(ctx, Message{Name: "ConnectFour.MakeMove"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Call error = %v; want DeadlineExceeded", err)
+	}
+}
@@ -1,6 +1,22 @@
 package rpcclient
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNotConnected means that the request wasn't sent because the transport is reconnecting.
+	ErrNotConnected = errors.New("rpc: not connected")
+	// ErrConnectionLost means that the connection dropped while waiting for the response.
+	// The request may or may not have been processed.
+	ErrConnectionLost = errors.New("rpc: connection lost")
+	// ErrClosed means that the client has been closed.
+	ErrClosed = errors.New("rpc: client closed")
+	// ErrUnavailable means that the target service can't handle requests at the moment, e.g. the gateway
+	// in front of it answered with 502, 503 or 504. It's wrapped together with ErrNotConnected or ErrConnectionLost.
+	ErrUnavailable = errors.New("rpc: service unavailable")
+)
 
 type RpcClient interface {
 	Call(ctx context.Context, req Message) (Message, error)
@@ -8,6 +24,11 @@ type RpcClient interface {
 }
 
 type Message struct {
-	Name string
-	Body []byte
+	Name string `json:"name"`
+	Body []byte `json:"body"`
+}
+
+// IsRetryable reports whether the call failed because of a transport problem that is likely temporary.
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrNotConnected) || errors.Is(err, ErrConnectionLost) || errors.Is(err, ErrUnavailable)
 }
@@ -0,0 +1,185 @@
+package rpcclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// MessageNameHeader carries the message name in both directions, the body only contains the message itself.
+const MessageNameHeader = "X-Message-Name"
+
+type HttpEncoding string
+
+const (
+	HttpEncodingProtobuf HttpEncoding = "protobuf"
+	// HttpEncodingJson sends protojson, which is easier to inspect and to serve from other stacks.
+	HttpEncodingJson HttpEncoding = "json"
+)
+
+type HttpOptions struct {
+	Encoding            HttpEncoding
+	Timeout             time.Duration // Limits every request, in addition to the deadline of the context. 0 means none.
+	KeepAlive           time.Duration
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+}
+
+type httpRpcClient struct {
+	url        string
+	options    HttpOptions
+	httpClient *http.Client
+}
+
+// NewHttpRpcClient posts every request to the gateway at url and expects the response in the response body.
+func NewHttpRpcClient(url string, options HttpOptions) RpcClient {
+	dialer := &net.Dialer{Timeout: options.Timeout, KeepAlive: options.KeepAlive}
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        options.MaxIdleConnsPerHost,
+		MaxIdleConnsPerHost: options.MaxIdleConnsPerHost,
+		IdleConnTimeout:     options.IdleConnTimeout,
+	}
+
+	return &httpRpcClient{
+		url:        url,
+		options:    options,
+		httpClient: &http.Client{Transport: transport},
+	}
+}
+
+func (c *httpRpcClient) Call(ctx context.Context, req Message) (Message, error) {
+	if c.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.options.Timeout)
+		defer cancel()
+	}
+
+	body, err := c.encode(req)
+	if err != nil {
+		return Message{}, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return Message{}, err
+	}
+	httpReq.Header.Set("Content-Type", c.contentType())
+	httpReq.Header.Set("Accept", c.contentType())
+	httpReq.Header.Set(MessageNameHeader, req.Name)
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return Message{}, httpTransportError(ctx, err)
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return Message{}, httpTransportError(ctx, err)
+	}
+
+	switch {
+	case httpResp.StatusCode == http.StatusServiceUnavailable:
+		return Message{}, fmt.Errorf("%w: %w: gateway: %s", ErrNotConnected, ErrUnavailable, httpResp.Status)
+	case httpResp.StatusCode == http.StatusBadGateway || httpResp.StatusCode == http.StatusGatewayTimeout:
+		return Message{}, fmt.Errorf("%w: %w: gateway: %s", ErrConnectionLost, ErrUnavailable, httpResp.Status)
+	case httpResp.StatusCode < 200 || httpResp.StatusCode > 299:
+		return Message{}, fmt.Errorf("rpc: gateway: %s: %s", httpResp.Status, bytes.TrimSpace(respBody))
+	}
+
+	return c.decode(Message{Name: httpResp.Header.Get(MessageNameHeader), Body: respBody})
+}
+
+func (c *httpRpcClient) Close() error {
+	c.httpClient.CloseIdleConnections()
+
+	return nil
+}
+
+func (c *httpRpcClient) contentType() string {
+	if c.options.Encoding == HttpEncodingJson {
+		return "application/json"
+	}
+
+	return "application/x-protobuf"
+}
+
+// encode converts the protobuf body of the message to the wire encoding.
+func (c *httpRpcClient) encode(msg Message) ([]byte, error) {
+	if c.options.Encoding != HttpEncodingJson {
+		return msg.Body, nil
+	}
+
+	decoded, err := newProtoMessage(msg.Name)
+	if err != nil {
+		return nil, err
+	} else if err := proto.Unmarshal(msg.Body, decoded); err != nil {
+		return nil, err
+	}
+
+	return protojson.Marshal(decoded)
+}
+
+// decode converts the body of the message from the wire encoding to protobuf.
+func (c *httpRpcClient) decode(msg Message) (Message, error) {
+	if msg.Name == "" {
+		return Message{}, fmt.Errorf("rpc: gateway: response without %s header", MessageNameHeader)
+	} else if c.options.Encoding != HttpEncodingJson {
+		return msg, nil
+	}
+
+	decoded, err := newProtoMessage(msg.Name)
+	if err != nil {
+		return Message{}, err
+	} else if err := protojson.Unmarshal(msg.Body, decoded); err != nil {
+		return Message{}, err
+	}
+
+	body, err := proto.Marshal(decoded)
+
+	return Message{Name: msg.Name, Body: body}, err
+}
+
+// newProtoMessage finds the generated type by the platform's naming convention,
+// e.g. "ConnectFour.MakeMove" is the protobuf message "connectfour.v1.MakeMove".
+func newProtoMessage(name string) (proto.Message, error) {
+	service, message, ok := strings.Cut(name, ".")
+	if !ok {
+		return nil, fmt.Errorf("rpc: invalid message name %q", name)
+	}
+
+	fullName := protoreflect.FullName(strings.ToLower(service) + ".v1." + message)
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(fullName)
+	if err != nil {
+		return nil, fmt.Errorf("rpc: no protobuf type %s for %s: %w", fullName, name, err)
+	}
+
+	return messageType.New().Interface(), nil
+}
+
+// httpTransportError tells requests that weren't sent apart from requests that may have been processed.
+func httpTransportError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return fmt.Errorf("%w: %w", ErrNotConnected, err)
+	}
+
+	return fmt.Errorf("%w: %w", ErrConnectionLost, err)
+}
@@ -0,0 +1,129 @@
+package rpcclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	identityv1 "github.com/gaming-platform/api/go/identity/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestGateway(t *testing.T, handler http.HandlerFunc) string {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func TestHttpRpcClientProtobuf(t *testing.T) {
+	url := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req identityv1.RegisterBot
+		if r.Header.Get(MessageNameHeader) != identityv1.RegisterBotType || proto.Unmarshal(body, &req) != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		resp, _ := proto.Marshal(&identityv1.RegisterBotResponse{BotId: "bot-" + req.Username})
+		w.Header().Set(MessageNameHeader, identityv1.RegisterBotResponseType)
+		_, _ = w.Write(resp)
+	})
+	client := NewHttpRpcClient(url, HttpOptions{Encoding: HttpEncodingProtobuf})
+
+	reqBody, _ := proto.Marshal(&identityv1.RegisterBot{Username: "test"})
+	resp, err := client.Call(context.Background(), Message{Name: identityv1.RegisterBotType, Body: reqBody})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var regResp identityv1.RegisterBotResponse
+	if resp.Name != identityv1.RegisterBotResponseType || proto.Unmarshal(resp.Body, &regResp) != nil || regResp.BotId != "bot-test" {
+		t.Fatalf("Call = %v; want RegisterBotResponse for bot-test", resp)
+	}
+}
+
+func TestHttpRpcClientJson(t *testing.T) {
+	url := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req identityv1.RegisterBot
+		if r.Header.Get("Content-Type") != "application/json" || protojson.Unmarshal(body, &req) != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		resp, _ := protojson.Marshal(&identityv1.RegisterBotResponse{BotId: "bot-" + req.Username})
+		w.Header().Set(MessageNameHeader, identityv1.RegisterBotResponseType)
+		_, _ = w.Write(resp)
+	})
+	client := NewHttpRpcClient(url, HttpOptions{Encoding: HttpEncodingJson})
+
+	reqBody, _ := proto.Marshal(&identityv1.RegisterBot{Username: "test"})
+	resp, err := client.Call(context.Background(), Message{Name: identityv1.RegisterBotType, Body: reqBody})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var regResp identityv1.RegisterBotResponse
+	if proto.Unmarshal(resp.Body, &regResp) != nil || regResp.BotId != "bot-test" {
+		t.Fatalf("Call = %v; want protobuf RegisterBotResponse for bot-test", resp)
+	}
+}
+
+func TestHttpRpcClientErrors(t *testing.T) {
+	for status, want := range map[int]error{
+		http.StatusServiceUnavailable: ErrNotConnected,
+		http.StatusGatewayTimeout:     ErrConnectionLost,
+	} {
+		url := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		})
+		client := NewHttpRpcClient(url, HttpOptions{})
+
+		_, err := client.Call(context.Background(), Message{Name: "Test.Query"})
+		if !errors.Is(err, want) || !errors.Is(err, ErrUnavailable) {
+			t.Fatalf("status %d: Call error = %v; want %v and ErrUnavailable", status, err, want)
+		}
+	}
+
+	url := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {})
+	client := NewHttpRpcClient(url, HttpOptions{})
+	_ = client.Close()
+	if _, err := client.Call(context.Background(), Message{Name: "Test.Query"}); err == nil {
+		t.Fatal("Call without response name succeeded; want error")
+	}
+}
+
+func TestHttpRpcClientUnreachableGateway(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	client := NewHttpRpcClient(url, HttpOptions{})
+	if _, err := client.Call(context.Background(), Message{Name: "Test.Query"}); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("Call error = %v; want ErrNotConnected", err)
+	}
+}
+
+func TestHttpRpcClientTimeoutAppliesBelowTheAttemptTimeout(t *testing.T) {
+	url := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	client := NewRetryClient(
+		NewCircuitBreakerClient(NewHttpRpcClient(url, HttpOptions{Timeout: 50 * time.Millisecond}), CircuitBreakerOptions{}),
+		RetryOptions{AttemptTimeout: time.Minute, DefaultPolicy: RetryPolicy{MaxAttempts: 1}},
+	)
+
+	start := time.Now()
+	if _, err := client.Call(context.Background(), Message{Name: "Test.Query"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Call error = %v; want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Call took %v; want the HTTP timeout to apply", elapsed)
+	}
+}
@@ -2,6 +2,7 @@ package rpcclient
 
 import (
 	"context"
+	"errors"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -36,7 +37,7 @@ func (c *prometheusClient) Call(ctx context.Context, req Message) (Message, erro
 
 	resp, err := c.rpcClient.Call(ctx, req)
 	if err != nil {
-		totalRpcCallsCounter.With(map[string]string{"request": req.Name, "result": "error"}).Inc()
+		totalRpcCallsCounter.With(map[string]string{"request": req.Name, "result": errorResult(err)}).Inc()
 
 		return Message{}, err
 	}
@@ -49,3 +50,16 @@ func (c *prometheusClient) Call(ctx context.Context, req Message) (Message, erro
 func (c *prometheusClient) Close() error {
 	return c.rpcClient.Close()
 }
+
+func errorResult(err error) string {
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrUnroutable):
+		return "unroutable"
+	case errors.Is(err, ErrRejected):
+		return "rejected"
+	default:
+		return "error"
+	}
+}
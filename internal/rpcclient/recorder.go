@@ -0,0 +1,162 @@
+package rpcclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrNoRecording means that the replaying client has no (more) recorded response for the request.
+var ErrNoRecording = errors.New("rpc: no recording")
+
+// RecordedCall is one request and its outcome. Recordings are stored as one JSON object per line.
+type RecordedCall struct {
+	Time     time.Time     `json:"time"`
+	Request  Message       `json:"request"`
+	Response Message       `json:"response"`
+	Latency  time.Duration `json:"latency"`
+	Error    string        `json:"error,omitempty"`
+	// ErrorKind is the well-known error the call failed with, so it can be replayed with errors.Is working.
+	ErrorKind string `json:"errorKind,omitempty"`
+}
+
+// The well-known errors with their ErrorKind, most specific first.
+var recordedErrorKinds = []struct {
+	kind string
+	err  error
+}{
+	{"not_connected", ErrNotConnected},
+	{"connection_lost", ErrConnectionLost},
+	{"closed", ErrClosed},
+	{"unroutable", ErrUnroutable},
+	{"rejected", ErrRejected},
+	{"circuit_open", ErrCircuitOpen},
+	{"deadline_exceeded", context.DeadlineExceeded},
+	{"canceled", context.Canceled},
+}
+
+type recordingClient struct {
+	rpcClient RpcClient
+	encoder   *json.Encoder
+	mutex     sync.Mutex
+}
+
+// NewRecordingClient writes every call to w, e.g. to turn a production incident into a regression test
+// with NewReplayingClient. Failing writes don't fail the calls.
+func NewRecordingClient(rpcClient RpcClient, w io.Writer) RpcClient {
+	return &recordingClient{rpcClient: rpcClient, encoder: json.NewEncoder(w)}
+}
+
+func (c *recordingClient) Call(ctx context.Context, req Message) (Message, error) {
+	start := time.Now()
+	resp, err := c.rpcClient.Call(ctx, req)
+
+	call := RecordedCall{Time: start, Request: req, Response: resp, Latency: time.Since(start)}
+	if err != nil {
+		call.Error = err.Error()
+		call.ErrorKind = errorKind(err)
+	}
+
+	c.mutex.Lock()
+	_ = c.encoder.Encode(call)
+	c.mutex.Unlock()
+
+	return resp, err
+}
+
+func (c *recordingClient) Close() error {
+	return c.rpcClient.Close()
+}
+
+func errorKind(err error) string {
+	for _, known := range recordedErrorKinds {
+		if errors.Is(err, known.err) {
+			return known.kind
+		}
+	}
+
+	return ""
+}
+
+func errorOfKind(kind string) (error, bool) {
+	for _, known := range recordedErrorKinds {
+		if known.kind == kind {
+			return known.err, true
+		}
+	}
+
+	return nil, false
+}
+
+type replayingClient struct {
+	calls []RecordedCall
+	used  []bool
+	mutex sync.Mutex
+}
+
+// NewReplayingClient answers requests with the recorded responses. A request is matched to the first unused
+// recording with the same name and body, so repeated identical requests get their responses in recorded order.
+func NewReplayingClient(r io.Reader) (RpcClient, error) {
+	calls, err := ReadRecording(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &replayingClient{calls: calls, used: make([]bool, len(calls))}, nil
+}
+
+// ReadRecording parses the output of a recording client.
+func ReadRecording(r io.Reader) ([]RecordedCall, error) {
+	var calls []RecordedCall
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var call RecordedCall
+		if err := json.Unmarshal(scanner.Bytes(), &call); err != nil {
+			return nil, fmt.Errorf("recording line %d: %w", line, err)
+		}
+		calls = append(calls, call)
+	}
+
+	return calls, scanner.Err()
+}
+
+func (c *replayingClient) Call(ctx context.Context, req Message) (Message, error) {
+	if err := ctx.Err(); err != nil {
+		return Message{}, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, call := range c.calls {
+		if c.used[i] || call.Request.Name != req.Name || !bytes.Equal(call.Request.Body, req.Body) {
+			continue
+		}
+		c.used[i] = true
+
+		if call.Error == "" {
+			return call.Response, nil
+		} else if knownErr, ok := errorOfKind(call.ErrorKind); ok {
+			return Message{}, fmt.Errorf("%w (recorded: %s)", knownErr, call.Error)
+		}
+
+		return Message{}, errors.New(call.Error)
+	}
+
+	return Message{}, fmt.Errorf("%w for %s", ErrNoRecording, req.Name)
+}
+
+func (c *replayingClient) Close() error {
+	return nil
+}
@@ -0,0 +1,70 @@
+package rpcclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRecordingCanBeReplayed(t *testing.T) {
+	var recording bytes.Buffer
+	calls := 0
+	client := NewRecordingClient(
+		funcClient(func(ctx context.Context, req Message) (Message, error) {
+			calls++
+			switch calls {
+			case 1:
+				return Message{Name: "Test.QueryResponse", Body: []byte{1}}, nil
+			case 2:
+				return Message{}, ErrConnectionLost
+			default:
+				return Message{Name: "Test.QueryResponse", Body: []byte{3}}, nil
+			}
+		}),
+		&recording,
+	)
+
+	query := Message{Name: "Test.Query", Body: []byte("q")}
+	_, _ = client.Call(context.Background(), query)
+	_, _ = client.Call(context.Background(), Message{Name: "Test.Command"})
+	_, _ = client.Call(context.Background(), query)
+
+	replay, err := NewReplayingClient(&recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp, err := replay.Call(context.Background(), query); err != nil || resp.Body[0] != 1 {
+		t.Fatalf("first query = %v, %v; want the first response", resp, err)
+	}
+	if resp, err := replay.Call(context.Background(), query); err != nil || resp.Body[0] != 3 {
+		t.Fatalf("second query = %v, %v; want the second response", resp, err)
+	}
+	if _, err := replay.Call(context.Background(), Message{Name: "Test.Command"}); !errors.Is(err, ErrConnectionLost) {
+		t.Fatalf("command error = %v; want ErrConnectionLost", err)
+	}
+	if _, err := replay.Call(context.Background(), query); !errors.Is(err, ErrNoRecording) {
+		t.Fatalf("third query error = %v; want ErrNoRecording", err)
+	}
+}
+
+func TestReplayMatchesBody(t *testing.T) {
+	var recording bytes.Buffer
+	client := NewRecordingClient(
+		funcClient(func(ctx context.Context, req Message) (Message, error) {
+			return Message{Name: "Test.QueryResponse", Body: req.Body}, nil
+		}),
+		&recording,
+	)
+	_, _ = client.Call(context.Background(), Message{Name: "Test.Query", Body: []byte("a")})
+
+	replay, err := NewReplayingClient(&recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := replay.Call(context.Background(), Message{Name: "Test.Query", Body: []byte("b")}); !errors.Is(err, ErrNoRecording) {
+		t.Fatalf("Call error = %v; want ErrNoRecording", err)
+	}
+}
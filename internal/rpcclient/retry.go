@@ -0,0 +1,112 @@
+package rpcclient
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var rpcCallAttempts = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "app_rpc_call_attempts",
+	Help:    "The number of attempts needed per RPC call.",
+	Buckets: []float64{1, 2, 3, 5, 8},
+}, []string{"request"})
+
+// errAttemptTimedOut is the cause of the context of an attempt that ran out of RetryOptions.AttemptTimeout.
+var errAttemptTimedOut = errors.New("rpc: attempt timed out")
+
+type RetryPolicy struct {
+	MaxAttempts int
+	// Idempotent requests are also retried after timeouts and lost connections,
+	// i.e. when the request may have been processed already.
+	// Other requests are only retried if they certainly weren't sent.
+	Idempotent bool
+}
+
+type RetryOptions struct {
+	// AttemptTimeout limits each attempt. The context passed to Call limits all attempts together.
+	AttemptTimeout time.Duration
+	MinBackoff     time.Duration
+	MaxBackoff     time.Duration
+	DefaultPolicy  RetryPolicy
+	Policies       map[string]RetryPolicy // By message name.
+}
+
+type retryClient struct {
+	rpcClient RpcClient
+	options   RetryOptions
+}
+
+func NewRetryClient(rpcClient RpcClient, options RetryOptions) RpcClient {
+	return &retryClient{rpcClient: rpcClient, options: options}
+}
+
+func (c *retryClient) Call(ctx context.Context, req Message) (Message, error) {
+	policy, ok := c.options.Policies[req.Name]
+	if !ok {
+		policy = c.options.DefaultPolicy
+	}
+
+	attempts := 0
+	defer func() {
+		rpcCallAttempts.With(map[string]string{"request": req.Name}).Observe(float64(attempts))
+	}()
+
+	for {
+		attempts++
+		resp, err := c.attempt(ctx, req)
+		if err == nil || attempts >= policy.MaxAttempts || !c.shouldRetry(ctx, err, policy) {
+			return resp, err
+		}
+
+		select {
+		case <-time.After(c.backoff(attempts)):
+		case <-ctx.Done():
+			return Message{}, err
+		}
+	}
+}
+
+func (c *retryClient) Close() error {
+	return c.rpcClient.Close()
+}
+
+func (c *retryClient) attempt(ctx context.Context, req Message) (Message, error) {
+	if c.options.AttemptTimeout <= 0 {
+		return c.rpcClient.Call(ctx, req)
+	}
+
+	ctx, cancel := context.WithTimeoutCause(ctx, c.options.AttemptTimeout, errAttemptTimedOut)
+	defer cancel()
+
+	return c.rpcClient.Call(ctx, req)
+}
+
+func (c *retryClient) shouldRetry(ctx context.Context, err error, policy RetryPolicy) bool {
+	if ctx.Err() != nil {
+		return false // The caller gave up, not only the attempt.
+	}
+
+	if errors.Is(err, ErrNotConnected) {
+		return true
+	}
+
+	return policy.Idempotent && (errors.Is(err, ErrConnectionLost) || errors.Is(err, context.DeadlineExceeded))
+}
+
+// backoff grows exponentially with the number of attempts and is jittered to spread retries of concurrent calls.
+func (c *retryClient) backoff(attempts int) time.Duration {
+	d := c.options.MinBackoff << (attempts - 1)
+	if d <= 0 || d > c.options.MaxBackoff {
+		d = c.options.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
@@ -0,0 +1,127 @@
+package rpcclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type funcClient func(ctx context.Context, req Message) (Message, error)
+
+func (f funcClient) Call(ctx context.Context, req Message) (Message, error) {
+	return f(ctx, req)
+}
+
+func (f funcClient) Close() error {
+	return nil
+}
+
+// failingClient fails with the given errors in order, then succeeds.
+func failingClient(calls *int, errs ...error) RpcClient {
+	return funcClient(func(ctx context.Context, req Message) (Message, error) {
+		*calls++
+		if *calls <= len(errs) {
+			return Message{}, errs[*calls-1]
+		}
+		return Message{Name: req.Name + "Response"}, nil
+	})
+}
+
+func newTestRetryClient(rpcClient RpcClient) RpcClient {
+	return NewRetryClient(rpcClient, RetryOptions{
+		MinBackoff:    time.Millisecond,
+		MaxBackoff:    time.Millisecond,
+		DefaultPolicy: RetryPolicy{MaxAttempts: 3},
+		Policies: map[string]RetryPolicy{
+			"Test.Query": {MaxAttempts: 3, Idempotent: true},
+		},
+	})
+}
+
+func TestRetryUnsentRequests(t *testing.T) {
+	calls := 0
+	client := newTestRetryClient(failingClient(&calls, ErrNotConnected, ErrNotConnected))
+
+	resp, err := client.Call(context.Background(), Message{Name: "Test.Command"})
+	if err != nil || resp.Name != "Test.CommandResponse" {
+		t.Fatalf("Call = %v, %v; want success", resp, err)
+	}
+	if calls != 3 {
+		t.Fatalf("calls = %d; want 3", calls)
+	}
+}
+
+func TestRetryStopsAtMaxAttempts(t *testing.T) {
+	calls := 0
+	client := newTestRetryClient(failingClient(&calls, ErrNotConnected, ErrNotConnected, ErrNotConnected))
+
+	if _, err := client.Call(context.Background(), Message{Name: "Test.Command"}); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("Call error = %v; want ErrNotConnected", err)
+	}
+	if calls != 3 {
+		t.Fatalf("calls = %d; want 3", calls)
+	}
+}
+
+func TestRetryOnlyIdempotentRequestsAfterPossibleDelivery(t *testing.T) {
+	for _, err := range []error{ErrConnectionLost, context.DeadlineExceeded} {
+		calls := 0
+		client := newTestRetryClient(failingClient(&calls, err))
+		if _, got := client.Call(context.Background(), Message{Name: "Test.Command"}); !errors.Is(got, err) || calls != 1 {
+			t.Fatalf("command: Call error = %v after %d calls; want %v after 1 call", got, calls, err)
+		}
+
+		calls = 0
+		client = newTestRetryClient(failingClient(&calls, err))
+		if _, got := client.Call(context.Background(), Message{Name: "Test.Query"}); got != nil || calls != 2 {
+			t.Fatalf("query: Call error = %v after %d calls; want success after 2 calls", got, calls)
+		}
+	}
+}
+
+func TestRetryNeverRetriesOtherErrors(t *testing.T) {
+	calls := 0
+	errOther := errors.New("other")
+	client := newTestRetryClient(failingClient(&calls, errOther))
+
+	if _, err := client.Call(context.Background(), Message{Name: "Test.Query"}); !errors.Is(err, errOther) || calls != 1 {
+		t.Fatalf("Call error = %v after %d calls; want other after 1 call", err, calls)
+	}
+}
+
+func TestRetryGivesUpWhenCallerIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	client := newTestRetryClient(funcClient(func(ctx context.Context, req Message) (Message, error) {
+		calls++
+		cancel()
+		return Message{}, ErrNotConnected
+	}))
+
+	if _, err := client.Call(ctx, Message{Name: "Test.Query"}); !errors.Is(err, ErrNotConnected) || calls != 1 {
+		t.Fatalf("Call error = %v after %d calls; want ErrNotConnected after 1 call", err, calls)
+	}
+}
+
+func TestRetryAppliesAttemptTimeout(t *testing.T) {
+	calls := 0
+	client := NewRetryClient(
+		funcClient(func(ctx context.Context, req Message) (Message, error) {
+			calls++
+			if calls == 1 {
+				<-ctx.Done()
+				return Message{}, ctx.Err()
+			}
+			return Message{Name: "Test.QueryResponse"}, nil
+		}),
+		RetryOptions{
+			AttemptTimeout: 10 * time.Millisecond,
+			DefaultPolicy:  RetryPolicy{MaxAttempts: 2, Idempotent: true},
+		},
+	)
+
+	if _, err := client.Call(context.Background(), Message{Name: "Test.Query"}); err != nil || calls != 2 {
+		t.Fatalf("Call error = %v after %d calls; want success after 2 calls", err, calls)
+	}
+}
@@ -2,90 +2,182 @@ package sse
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	externalsse "github.com/tmaxmax/go-sse"
 )
 
+var reconnectsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "app_sse_reconnects_total",
+	Help: "The number of reconnected SSE streams, by whether they resumed from the last event ID.",
+}, []string{"resumed"})
+
 type ConnectChannelResult struct {
 	Event any
+	// Error is only set on the last result, when the stream ended for good. Problems the stream
+	// recovers from are delivered as Gap.
 	Error error
 }
 
+// Gap is delivered as event when events may have been lost. Subscribers should reload the state they track.
+type Gap struct {
+	Channel string
+	Cause   error // ErrNotResumable, ErrOverflow or a *DecodeError.
+}
+
+// ErrNotResumable means the stream reconnected without an event ID to resume from.
+var ErrNotResumable = errors.New("sse: reconnected without event ID")
+
+// ErrOverflow means events were dropped because the subscriber didn't keep up, see BackpressureDrop.
+var ErrOverflow = errors.New("sse: subscriber buffer overflowed")
+
+// DecodeError is an event that couldn't be decoded. The event is skipped, the stream continues.
+type DecodeError struct {
+	Event string
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("sse: decode %s: %v", e.Event, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func gapCause(err error) string {
+	var decodeErr *DecodeError
+	switch {
+	case errors.Is(err, ErrNotResumable):
+		return "not_resumable"
+	case errors.Is(err, ErrOverflow):
+		return "overflow"
+	case errors.As(err, &decodeErr):
+		return "undecodable"
+	default:
+		return "unknown"
+	}
+}
+
+type ClientOptions struct {
+	// MaxReconnects limits the consecutive failed attempts to reconnect a lost stream. 0 disables reconnecting.
+	MaxReconnects int
+	// ReconnectInterval is the initial wait before reconnecting, it grows with every failed attempt.
+	// 0 means go-sse's default of 500ms.
+	ReconnectInterval time.Duration
+	// BufferSize limits the events queued for a subscriber that doesn't keep up. 0 means no limit.
+	BufferSize int
+	// Backpressure applies when the buffer is full. The default is BackpressureBlock.
+	Backpressure Backpressure
+	// Recorder, if set, gets the raw messages of all subscriptions.
+	Recorder *Recorder
+}
+
 type Client struct {
 	NchanSubUrl string
+	options     ClientOptions
 }
 
-func NewClient(nchanSubUrl string) *Client {
+func NewClient(nchanSubUrl string, options ClientOptions) *Client {
 	return &Client{
 		NchanSubUrl: nchanSubUrl,
+		options:     options,
 	}
 }
 
 func (s *Client) Connect(ctx context.Context, sseCh string) (chan ConnectChannelResult, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", s.NchanSubUrl+"?id="+sseCh, nil)
+	req, err := s.newRequest(ctx, []string{sseCh})
 	if err != nil {
 		return nil, err
 	}
 
-	resChan := make(chan ConnectChannelResult, 1)
+	sub := newSubscriber(sseCh, s.options)
+	context.AfterFunc(ctx, sub.close)
 	go (func() {
-		defer close(resChan)
-		client := externalsse.Client{Backoff: externalsse.Backoff{MaxRetries: -1}}
-		conn := client.NewConnection(req)
-		unsubscribe := conn.SubscribeToAll(func(e externalsse.Event) {
-			parts := strings.SplitN(e.Data, ":", 3)
-			if len(parts) < 3 {
-				return
-			}
+		err := s.stream(
+			req,
+			nil,
+			func() { sub.pushGap(ErrNotResumable) },
+			func(id string, data string) {
+				s.options.Recorder.Record(sseCh, id, data)
 
-			payload := []byte(parts[2])
-			var event any
-			var err error
-
-			switch parts[0] {
-			case "ConnectFour.GameOpened":
-				event, err = castPayloadToEvent[GameOpened](payload)
-			case "ConnectFour.PlayerJoined":
-				event, err = castPayloadToEvent[PlayerJoined](payload)
-			case "ConnectFour.ChatAssigned":
-				event, err = castPayloadToEvent[ChatAssigned](payload)
-			case "ConnectFour.PlayerMoved":
-				event, err = castPayloadToEvent[PlayerMoved](payload)
-			case "ConnectFour.GameAborted":
-				event, err = castPayloadToEvent[GameAborted](payload)
-			case "ConnectFour.GameWon":
-				event, err = castPayloadToEvent[GameWon](payload)
-			case "ConnectFour.GameDrawn":
-				event, err = castPayloadToEvent[GameDrawn](payload)
-			case "ConnectFour.GameTimedOut":
-				event, err = castPayloadToEvent[GameTimedOut](payload)
-			case "ConnectFour.GameResigned":
-				event, err = castPayloadToEvent[GameResigned](payload)
-			default:
-				return
-			}
+				event, _, err := ParseEvent(data)
+				if err != nil {
+					sub.pushGap(err)
+				} else if event != nil {
+					sub.push(ConnectChannelResult{Event: event})
+				}
+			},
+		)
+		sub.finish(err)
+	})()
 
-			if err != nil {
-				resChan <- ConnectChannelResult{Error: err}
-				return
-			}
+	return sub.out, nil
+}
 
-			resChan <- ConnectChannelResult{Event: event}
-		})
-		defer unsubscribe()
+// newRequest subscribes to all given channels with Nchan's multi-channel subscribe.
+func (s *Client) newRequest(ctx context.Context, sseChs []string) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, "GET", s.NchanSubUrl+"?id="+strings.Join(sseChs, ","), nil)
+}
 
-		resChan <- ConnectChannelResult{Error: conn.Connect()}
-	})()
+// stream reads the events until the connection ends. It calls connected once the server accepted the subscription,
+// and handle with the ID and the data of every message.
+// A lost connection is reestablished with the ID of the last event, so Nchan resends what was missed.
+// If no event has been received yet, there's no ID to resume from and gap is called instead.
+func (s *Client) stream(
+	req *http.Request,
+	connected func(),
+	gap func(),
+	handle func(id string, data string),
+) error {
+	// Both are only accessed by the goroutine of conn.Connect.
+	var lastEventId string
+	connects := 0
 
-	return resChan, nil
+	client := externalsse.Client{
+		Backoff: s.backoff(),
+		ResponseValidator: func(r *http.Response) error {
+			if err := externalsse.DefaultValidator(r); err != nil {
+				return err
+			}
+
+			connects++
+			if connects > 1 {
+				reconnectsCounter.With(map[string]string{"resumed": strconv.FormatBool(lastEventId != "")}).Inc()
+				if lastEventId == "" {
+					gap()
+				}
+			}
+			if connected != nil {
+				connected()
+			}
+			return nil
+		},
+	}
+	conn := client.NewConnection(req)
+	unsubscribe := conn.SubscribeToAll(func(e externalsse.Event) {
+		lastEventId = e.LastEventID
+		handle(e.LastEventID, e.Data)
+	})
+	defer unsubscribe()
+
+	return conn.Connect()
 }
 
-func castPayloadToEvent[T any](payload []byte) (T, error) {
-	var event T
-	err := json.Unmarshal(payload, &event)
+func (s *Client) backoff() externalsse.Backoff {
+	if s.options.MaxReconnects <= 0 {
+		return externalsse.Backoff{MaxRetries: -1}
+	}
 
-	return event, err
+	return externalsse.Backoff{
+		InitialInterval: s.options.ReconnectInterval,
+		MaxRetries:      s.options.MaxReconnects,
+	}
 }
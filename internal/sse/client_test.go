@@ -0,0 +1,97 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+var reconnectingOptions = ClientOptions{MaxReconnects: 5, ReconnectInterval: 50 * time.Millisecond}
+
+func TestClientResumesFromLastEventId(t *testing.T) {
+	nchan := newTestNchan(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results, err := NewClient(nchan.server.URL, reconnectingOptions).Connect(ctx, "connect-four-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nchan.waitForConnections(t, "connect-four-1")
+	nchan.publish("connect-four-1", `ConnectFour.PlayerMoved:1:{"x":1,"y":6,"color":1,"nextPlayerId":"b"}`)
+	receive(t, results)
+
+	nchan.drop()
+	nchan.publish("connect-four-1", `ConnectFour.GameWon:2:{"gameId":"1"}`) // Published while disconnected.
+
+	if res := receive(t, results); res.Event != (GameWon{GameId: "1"}) {
+		t.Fatalf("received %+v after reconnecting; want GameWon", res)
+	}
+	if ids := nchan.requestedLastEventIds(); !slices.Equal(ids, []string{"", "1:0"}) {
+		t.Fatalf("requests with Last-Event-ID %q; want none, then 1:0", ids)
+	}
+}
+
+func TestClientReportsGapWithoutLastEventId(t *testing.T) {
+	nchan := newTestNchan(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results, err := NewClient(nchan.server.URL, reconnectingOptions).Connect(ctx, "connect-four-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nchan.waitForConnections(t, "connect-four-1")
+	nchan.drop()
+
+	if res := receive(t, results); res.Event != (Gap{Channel: "connect-four-1", Cause: ErrNotResumable}) {
+		t.Fatalf("received %+v after reconnecting; want Gap", res)
+	}
+}
+
+func TestClientFailsWithoutReconnects(t *testing.T) {
+	nchan := newTestNchan(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results, err := NewClient(nchan.server.URL, ClientOptions{}).Connect(ctx, "connect-four-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nchan.waitForConnections(t, "connect-four-1")
+	nchan.drop()
+
+	if res := receive(t, results); res.Error == nil {
+		t.Fatalf("received %+v after the connection was lost; want an error", res)
+	}
+}
+
+func TestClientReportsUndecodableEventAsGap(t *testing.T) {
+	nchan := newTestNchan(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results, err := NewClient(nchan.server.URL, ClientOptions{}).Connect(ctx, "connect-four-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nchan.waitForConnections(t, "connect-four-1")
+	nchan.publish("connect-four-1", `ConnectFour.PlayerMoved:1:{"gameId":"1","x":"one"}`)
+
+	res := receive(t, results)
+	gap, ok := res.Event.(Gap)
+	var decodeErr *DecodeError
+	if !ok || gap.Channel != "connect-four-1" || !errors.As(gap.Cause, &decodeErr) || res.Error != nil {
+		t.Fatalf("received %+v for an undecodable event; want Gap with DecodeError", res)
+	}
+	if decodeErr.Event != "ConnectFour.PlayerMoved" {
+		t.Fatalf("DecodeError.Event = %q; want ConnectFour.PlayerMoved", decodeErr.Event)
+	}
+
+	nchan.publish("connect-four-1", `ConnectFour.GameWon:2:{"gameId":"1"}`)
+	if res := receive(t, results); res.Event != (GameWon{GameId: "1"}) {
+		t.Fatalf("received %+v after the undecodable event; want GameWon", res)
+	}
+}
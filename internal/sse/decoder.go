@@ -0,0 +1,106 @@
+package sse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var droppedEventsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "app_sse_dropped_events_total",
+	Help: "The number of received events that weren't delivered, because they are unknown, undecodable or malformed.",
+}, []string{"event", "reason"})
+
+// ErrUnsupportedVersion means that the event is known, but there's no decoder for the version of its payload.
+var ErrUnsupportedVersion = errors.New("sse: unsupported event version")
+
+// Decoder turns the JSON payload of an event into the event.
+type Decoder func(payload []byte) (any, error)
+
+// decoders by event name and payload version.
+var decoders = map[string]map[int]Decoder{
+	"ConnectFour.GameOpened":   {1: decodeAs[GameOpened]},
+	"ConnectFour.PlayerJoined": {1: decodeAs[PlayerJoined]},
+	"ConnectFour.ChatAssigned": {1: decodeAs[ChatAssigned]},
+	"ConnectFour.PlayerMoved":  {1: decodeAs[PlayerMoved]},
+	"ConnectFour.GameAborted":  {1: decodeAs[GameAborted]},
+	"ConnectFour.GameWon":      {1: decodeAs[GameWon]},
+	"ConnectFour.GameDrawn":    {1: decodeAs[GameDrawn]},
+	"ConnectFour.GameTimedOut": {1: decodeAs[GameTimedOut]},
+	"ConnectFour.GameResigned": {1: decodeAs[GameResigned]},
+	"Chat.ChatInitiated":       {1: decodeAs[ChatInitiated]},
+	"Chat.MessageWritten":      {1: decodeAs[MessageWritten]},
+}
+
+// RegisterDecoder adds or replaces the decoder of a payload version of an event. It must be called before
+// any client connects, e.g. from an init function.
+func RegisterDecoder(name string, version int, decoder Decoder) {
+	if decoders[name] == nil {
+		decoders[name] = make(map[int]Decoder)
+	}
+	decoders[name][version] = decoder
+}
+
+// ParseEvent decodes the data of a message published by the platform, "<name>:<x>:<json payload>".
+// The payload version is taken from a ".v<version>" suffix of the name or the "version" field of the payload,
+// it's 1 without either. ParseEvent returns a nil event without error for unknown events, and a *DecodeError
+// if the payload doesn't fit or its version has no decoder. All are counted in app_sse_dropped_events_total.
+func ParseEvent(data string) (any, []byte, error) {
+	parts := strings.SplitN(data, ":", 3)
+	if len(parts) < 3 {
+		droppedEventsCounter.With(map[string]string{"event": "", "reason": "malformed"}).Inc()
+		return nil, nil, nil
+	}
+
+	payload := []byte(parts[2])
+	name, version := eventVersion(parts[0], payload)
+	versions, ok := decoders[name]
+	if !ok {
+		droppedEventsCounter.With(map[string]string{"event": name, "reason": "unknown"}).Inc()
+		return nil, payload, nil
+	}
+
+	decode, ok := versions[version]
+	if !ok {
+		droppedEventsCounter.With(map[string]string{"event": name, "reason": "unsupported_version"}).Inc()
+		return nil, payload, &DecodeError{Event: name, Err: fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)}
+	}
+
+	event, err := decode(payload)
+	if err != nil {
+		droppedEventsCounter.With(map[string]string{"event": name, "reason": "undecodable"}).Inc()
+		return nil, payload, &DecodeError{Event: name, Err: err}
+	}
+
+	return event, payload, nil
+}
+
+// eventVersion splits the version suffix off the event name, or reads the version from the payload.
+func eventVersion(name string, payload []byte) (string, int) {
+	if i := strings.LastIndex(name, ".v"); i >= 0 {
+		if version, err := strconv.Atoi(name[i+2:]); err == nil && version > 0 {
+			return name[:i], version
+		}
+	}
+
+	var versioned struct {
+		Version int `json:"version"`
+	}
+	if json.Unmarshal(payload, &versioned) == nil && versioned.Version > 0 {
+		return name, versioned.Version
+	}
+
+	return name, 1
+}
+
+func decodeAs[T any](payload []byte) (any, error) {
+	var event T
+	err := json.Unmarshal(payload, &event)
+
+	return event, err
+}
@@ -0,0 +1,131 @@
+package sse
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestParseEventDecodesFullPayloads(t *testing.T) {
+	tests := []struct {
+		data string
+		want any
+	}{
+		{
+			`ConnectFour.PlayerJoined:1:{"gameId":"g","opponentPlayerId":"y","redPlayerId":"r","yellowPlayerId":"y"}`,
+			PlayerJoined{GameId: "g", OpponentPlayerId: "y", RedPlayerId: "r", YellowPlayerId: "y"},
+		},
+		{
+			`ConnectFour.PlayerMoved:2:{"gameId":"g","x":3,"y":6,"color":1,"playerId":"r","nextPlayerId":"y"}`,
+			PlayerMoved{GameId: "g", X: 3, Y: 6, Color: 1, PlayerId: "r", NextPlayerId: "y"},
+		},
+		{
+			`ConnectFour.GameWon:3:{"gameId":"g","winnerPlayerId":"r"}`,
+			GameWon{GameId: "g", WinnerPlayerId: "r"},
+		},
+		{
+			`ConnectFour.GameResigned:4:{"gameId":"g","resignedPlayerId":"y","opponentPlayerId":"r"}`,
+			GameResigned{GameId: "g", ResignedPlayerId: "y", OpponentPlayerId: "r"},
+		},
+		{
+			`Chat.MessageWritten:5:{"chatId":"c","messageId":"m","authorId":"r","message":"gg","writtenAt":"2026-01-02T03:04:05Z"}`,
+			MessageWritten{ChatId: "c", MessageId: "m", AuthorId: "r", Message: "gg", WrittenAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+		},
+	}
+
+	for _, tt := range tests {
+		event, _, err := ParseEvent(tt.data)
+		if err != nil || event != tt.want {
+			t.Errorf("ParseEvent(%s) = %+v, %v; want %+v", tt.data, event, err, tt.want)
+		}
+	}
+}
+
+func TestParseEventToleratesFieldsWithinAVersion(t *testing.T) {
+	// An additional field, and no player.
+	event, _, err := ParseEvent(`ConnectFour.PlayerMoved:1:{"gameId":"g","x":1,"y":6,"color":2,"nextPlayerId":"r","turnEndsAt":"soon"}`)
+	if err != nil || event != (PlayerMoved{GameId: "g", X: 1, Y: 6, Color: 2, NextPlayerId: "r"}) {
+		t.Fatalf("ParseEvent = %+v, %v; want PlayerMoved without player", event, err)
+	}
+}
+
+func TestParseEventDispatchesOnPayloadVersion(t *testing.T) {
+	type PlayerMovedV2 struct {
+		GameId string `json:"gameId"`
+		Column int    `json:"column"`
+	}
+	RegisterDecoder("ConnectFour.PlayerMoved", 2, decodeAs[PlayerMovedV2])
+	t.Cleanup(func() { delete(decoders["ConnectFour.PlayerMoved"], 2) })
+
+	tests := []struct {
+		data string
+		want any
+	}{
+		{`ConnectFour.PlayerMoved:1:{"gameId":"g","x":1,"y":6,"color":1}`, PlayerMoved{GameId: "g", X: 1, Y: 6, Color: 1}},
+		{`ConnectFour.PlayerMoved:2:{"version":1,"gameId":"g","x":1,"y":6,"color":1}`, PlayerMoved{GameId: "g", X: 1, Y: 6, Color: 1}},
+		{`ConnectFour.PlayerMoved:3:{"version":2,"gameId":"g","column":1}`, PlayerMovedV2{GameId: "g", Column: 1}},
+		{`ConnectFour.PlayerMoved.v2:4:{"gameId":"g","column":1}`, PlayerMovedV2{GameId: "g", Column: 1}},
+	}
+	for _, tt := range tests {
+		event, _, err := ParseEvent(tt.data)
+		if err != nil || event != tt.want {
+			t.Errorf("ParseEvent(%s) = %+v, %v; want %+v", tt.data, event, err, tt.want)
+		}
+	}
+}
+
+func TestParseEventDropsUnsupportedVersions(t *testing.T) {
+	unsupported := droppedEventsCounter.With(map[string]string{"event": "ConnectFour.GameWon", "reason": "unsupported_version"})
+	before := testutil.ToFloat64(unsupported)
+
+	for _, data := range []string{
+		`ConnectFour.GameWon:1:{"version":3,"gameId":"g","winnerPlayerId":"r"}`,
+		`ConnectFour.GameWon.v3:2:{"gameId":"g","winnerPlayerId":"r"}`,
+	} {
+		event, _, err := ParseEvent(data)
+		var decodeErr *DecodeError
+		if event != nil || !errors.As(err, &decodeErr) || !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("ParseEvent(%s) = %+v, %v; want nil and a DecodeError with ErrUnsupportedVersion", data, event, err)
+		} else if decodeErr.Event != "ConnectFour.GameWon" {
+			t.Errorf("DecodeError.Event = %q; want ConnectFour.GameWon", decodeErr.Event)
+		}
+	}
+
+	if got := testutil.ToFloat64(unsupported) - before; got != 2 {
+		t.Errorf("counted %v events of unsupported versions; want 2", got)
+	}
+}
+
+func TestParseEventCountsDroppedEvents(t *testing.T) {
+	unknown := droppedEventsCounter.With(map[string]string{"event": "ConnectFour.GameRematched", "reason": "unknown"})
+	undecodable := droppedEventsCounter.With(map[string]string{"event": "ConnectFour.GameWon", "reason": "undecodable"})
+	unknownBefore, undecodableBefore := testutil.ToFloat64(unknown), testutil.ToFloat64(undecodable)
+
+	if event, _, err := ParseEvent(`ConnectFour.GameRematched:1:{"gameId":"g"}`); event != nil || err != nil {
+		t.Errorf("ParseEvent of an unknown event = %+v, %v; want nil, nil", event, err)
+	}
+	if event, _, err := ParseEvent(`ConnectFour.GameWon:2:{"gameId":7}`); event != nil || err == nil {
+		t.Errorf("ParseEvent of an undecodable event = %+v, %v; want nil and an error", event, err)
+	}
+
+	if got := testutil.ToFloat64(unknown) - unknownBefore; got != 1 {
+		t.Errorf("counted %v unknown events; want 1", got)
+	}
+	if got := testutil.ToFloat64(undecodable) - undecodableBefore; got != 1 {
+		t.Errorf("counted %v undecodable events; want 1", got)
+	}
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	type GameRematched struct {
+		GameId string `json:"gameId"`
+	}
+	RegisterDecoder("ConnectFour.GameRematched", 1, decodeAs[GameRematched])
+	t.Cleanup(func() { delete(decoders, "ConnectFour.GameRematched") })
+
+	if event, _, err := ParseEvent(`ConnectFour.GameRematched:1:{"gameId":"g"}`); err != nil || event != (GameRematched{GameId: "g"}) {
+		t.Fatalf("ParseEvent = %+v, %v; want GameRematched", event, err)
+	}
+}
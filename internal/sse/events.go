@@ -1,35 +1,51 @@
 package sse
 
-// Defining the bare minimum events and their properties needed for the bot to function.
+import "time"
+
+// The events the platform publishes to Nchan, in version 1 of their payloads. Other versions need a decoder of
+// their own, see RegisterDecoder. Within a version, payloads are decoded leniently: unknown fields are ignored
+// and missing fields keep their zero value.
+// Stones and colors are 1 for red and 2 for yellow.
 
 type GameOpened struct {
-	GameId   string `json:"gameId"`
-	PlayerId string `json:"playerId"`
-	Width    int    `json:"width"`
-	Height   int    `json:"height"`
+	GameId         string `json:"gameId"`
+	PlayerId       string `json:"playerId"`
+	Width          int    `json:"width"`
+	Height         int    `json:"height"`
+	PreferredStone int    `json:"preferredStone"`
+	Timer          string `json:"timer"`
 }
 
 type PlayerJoined struct {
-	GameId      string `json:"gameId"`
-	RedPlayerId string `json:"redPlayerId"`
+	GameId           string `json:"gameId"`
+	OpponentPlayerId string `json:"opponentPlayerId"` // The player who joined.
+	RedPlayerId      string `json:"redPlayerId"`
+	YellowPlayerId   string `json:"yellowPlayerId"`
 }
 
 type ChatAssigned struct {
+	GameId string `json:"gameId"`
 	ChatId string `json:"chatId"`
 }
 
 type PlayerMoved struct {
+	GameId       string `json:"gameId"`
 	X            int    `json:"x"`
 	Y            int    `json:"y"`
+	Color        int    `json:"color"`
+	PlayerId     string `json:"playerId"`
 	NextPlayerId string `json:"nextPlayerId"`
 }
 
 type GameAborted struct {
-	GameId string `json:"gameId"`
+	GameId           string `json:"gameId"`
+	AbortedPlayerId  string `json:"abortedPlayerId"`
+	OpponentPlayerId string `json:"opponentPlayerId"`
 }
 
 type GameWon struct {
-	GameId string `json:"gameId"`
+	GameId         string `json:"gameId"`
+	WinnerPlayerId string `json:"winnerPlayerId"`
 }
 
 type GameDrawn struct {
@@ -37,9 +53,26 @@ type GameDrawn struct {
 }
 
 type GameTimedOut struct {
-	GameId string `json:"gameId"`
+	GameId           string `json:"gameId"`
+	TimedOutPlayerId string `json:"timedOutPlayerId"`
+	OpponentPlayerId string `json:"opponentPlayerId"` // The winner.
 }
 
 type GameResigned struct {
-	GameId string `json:"gameId"`
+	GameId           string `json:"gameId"`
+	ResignedPlayerId string `json:"resignedPlayerId"`
+	OpponentPlayerId string `json:"opponentPlayerId"` // The winner.
+}
+
+type ChatInitiated struct {
+	ChatId  string `json:"chatId"`
+	OwnerId string `json:"ownerId"`
+}
+
+type MessageWritten struct {
+	ChatId    string    `json:"chatId"`
+	MessageId string    `json:"messageId"`
+	AuthorId  string    `json:"authorId"`
+	Message   string    `json:"message"`
+	WrittenAt time.Time `json:"writtenAt"`
 }
@@ -0,0 +1,433 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var multiplexedConnectionsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "app_sse_multiplexed_connections",
+	Help: "The number of Nchan connections the game channels are multiplexed over.",
+})
+
+var multiplexedChannelsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "app_sse_multiplexed_channels",
+	Help: "The number of game channels with at least one subscriber.",
+})
+
+var unroutableEventsCounter = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "app_sse_unroutable_events_total",
+	Help: "The number of events on multiplexed connections that couldn't be attributed to a game.",
+})
+
+// ErrMultiplexerClosed is returned by Connect after Close.
+var ErrMultiplexerClosed = errors.New("sse: multiplexer closed")
+
+// gameChannelPrefix marks the channels that are multiplexed. Their events are routed by the gameId in the payload.
+const gameChannelPrefix = "connect-four-"
+
+// nchanMaxChannels is the maximum number of channels Nchan accepts in one multi-channel subscription.
+const nchanMaxChannels = 255
+
+type MultiplexerOptions struct {
+	// MaxChannelsPerConnection limits the game channels per Nchan connection. 0 means Nchan's limit of 255.
+	MaxChannelsPerConnection int
+	// Debounce delays applying subscription changes, so games starting or ending together cause a single reconnect.
+	Debounce time.Duration
+}
+
+// Multiplexer subscribes to the game channels over a few shared Nchan connections and fans the events out.
+// Nchan fixes the channels of a connection when it's opened, so a connection is replaced whenever a channel
+// is added. Removed channels stay subscribed, their events are dropped, until less than half of the channels
+// of a connection are left and it's replaced with the remaining ones. The connection being replaced delivers
+// until the replacement is established, which resumes each channel after the last event dispatched of it,
+// see nchanMessageId. Events delivered by both are dispatched once, so events are neither lost nor delivered
+// twice. Channels without an event yet can't be resumed, events the replaced connection still had in flight
+// may be lost, so their subscribers get a Gap. Added channels start with the events published once the
+// replacement is established. A lost connection is resumed like with Client.Connect, the last event ID
+// of a multiplexed connection covers all of its channels.
+// Other channels, like the lobby, get a connection of their own.
+type Multiplexer struct {
+	client      *Client
+	options     MultiplexerOptions
+	subscribers map[string]map[*subscriber]struct{} // By channel.
+	assignments map[string]*muxConnection           // By channel.
+	connections []*muxConnection
+	applyTimer  *time.Timer // Set while changes are pending.
+	closed      bool
+	mutex       sync.Mutex
+}
+
+type muxConnection struct {
+	channels     map[string]struct{}
+	streamed     int // The number of channels the current stream subscribed to, removed ones included.
+	dirty        bool
+	stream       *muxStream                // The stream whose events are dispatched, nil if none.
+	pending      *muxStream                // The replacement of stream until it's established, see restart.
+	lastEventIds map[string]nchanMessageId // By channel, of the last event dispatched, the replacement resumes from them.
+}
+
+type muxStream struct {
+	cancel    context.CancelFunc
+	channels  []string
+	unresumed []string // Channels of the replaced stream without an event to resume from, see established.
+}
+
+// close ends the streams of the connection.
+func (c *muxConnection) close() {
+	for _, s := range []*muxStream{c.stream, c.pending} {
+		if s != nil {
+			s.cancel()
+		}
+	}
+	c.stream, c.pending = nil, nil
+}
+
+// replace makes the stream the one whose events are dispatched and closes the previous one.
+func (c *muxConnection) replace(s *muxStream) {
+	if c.stream != nil {
+		c.stream.cancel()
+	}
+	c.stream = s
+}
+
+func NewMultiplexer(client *Client, options MultiplexerOptions) *Multiplexer {
+	if options.MaxChannelsPerConnection <= 0 || options.MaxChannelsPerConnection > nchanMaxChannels {
+		options.MaxChannelsPerConnection = nchanMaxChannels
+	}
+
+	return &Multiplexer{
+		client:      client,
+		options:     options,
+		subscribers: make(map[string]map[*subscriber]struct{}),
+		assignments: make(map[string]*muxConnection),
+	}
+}
+
+// Connect subscribes to the channel until ctx is done, like Client.Connect.
+func (m *Multiplexer) Connect(ctx context.Context, sseCh string) (chan ConnectChannelResult, error) {
+	if !strings.HasPrefix(sseCh, gameChannelPrefix) {
+		return m.client.Connect(ctx, sseCh)
+	}
+
+	sub := newSubscriber(sseCh, m.client.options)
+
+	m.mutex.Lock()
+	if m.closed {
+		m.mutex.Unlock()
+		return nil, ErrMultiplexerClosed
+	}
+	if m.subscribers[sseCh] == nil {
+		m.subscribers[sseCh] = make(map[*subscriber]struct{})
+		m.assign(sseCh)
+	}
+	m.subscribers[sseCh][sub] = struct{}{}
+	m.mutex.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			m.remove(sseCh, sub)
+		case <-sub.done:
+		}
+	}()
+
+	return sub.out, nil
+}
+
+// Close ends all multiplexed subscriptions.
+func (m *Multiplexer) Close() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closed {
+		return
+	}
+	m.closed = true
+
+	if m.applyTimer != nil {
+		m.applyTimer.Stop()
+	}
+	for _, conn := range m.connections {
+		conn.close()
+	}
+	for _, subs := range m.subscribers {
+		for sub := range subs {
+			sub.close()
+		}
+	}
+	multiplexedConnectionsGauge.Sub(float64(len(m.connections)))
+	multiplexedChannelsGauge.Sub(float64(len(m.subscribers)))
+}
+
+// assign puts the channel on the first connection with room.
+func (m *Multiplexer) assign(sseCh string) {
+	var conn *muxConnection
+	for _, c := range m.connections {
+		if len(c.channels) < m.options.MaxChannelsPerConnection {
+			conn = c
+			break
+		}
+	}
+	if conn == nil {
+		conn = &muxConnection{channels: make(map[string]struct{}), lastEventIds: make(map[string]nchanMessageId)}
+		m.connections = append(m.connections, conn)
+		multiplexedConnectionsGauge.Inc()
+	}
+
+	conn.channels[sseCh] = struct{}{}
+	conn.dirty = true
+	m.assignments[sseCh] = conn
+	multiplexedChannelsGauge.Inc()
+	m.scheduleApply()
+}
+
+func (m *Multiplexer) remove(sseCh string, sub *subscriber) {
+	sub.close()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closed {
+		return
+	}
+
+	delete(m.subscribers[sseCh], sub)
+	if len(m.subscribers[sseCh]) > 0 {
+		return
+	}
+
+	delete(m.subscribers, sseCh)
+	conn := m.assignments[sseCh]
+	delete(m.assignments, sseCh)
+	delete(conn.channels, sseCh)
+	delete(conn.lastEventIds, sseCh)
+	multiplexedChannelsGauge.Dec()
+
+	// The stream keeps delivering the removed channel, dispatch drops its events. Reconnecting for each
+	// ended game would restart the stream of all other games on the connection, so it's compacted lazily.
+	if len(conn.channels) == 0 || len(conn.channels)*2 < conn.streamed {
+		conn.dirty = true
+		m.scheduleApply()
+	}
+}
+
+// applyLater applies the changes held back while the connection's replacement was pending, see apply.
+func (m *Multiplexer) applyLater(conn *muxConnection) {
+	if conn.dirty && !m.closed {
+		m.scheduleApply()
+	}
+}
+
+func (m *Multiplexer) scheduleApply() {
+	if m.applyTimer == nil {
+		m.applyTimer = time.AfterFunc(m.options.Debounce, m.apply)
+	}
+}
+
+// apply replaces the connections whose channels changed.
+func (m *Multiplexer) apply() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.applyTimer = nil
+	if m.closed {
+		return
+	}
+
+	for _, conn := range m.connections {
+		if !conn.dirty {
+			continue
+		}
+
+		if len(conn.channels) == 0 {
+			conn.dirty = false
+			conn.close()
+			continue
+		} else if conn.pending != nil {
+			continue // Applied once it's established, its events may already be on the way.
+		}
+
+		conn.dirty = false
+		m.restart(conn)
+	}
+
+	connections := len(m.connections)
+	m.connections = slices.DeleteFunc(m.connections, func(c *muxConnection) bool { return len(c.channels) == 0 })
+	multiplexedConnectionsGauge.Sub(float64(connections - len(m.connections)))
+}
+
+// restart opens a stream with the current channels that replaces the previous one once it's established,
+// not to lose the events in between. It resumes the channels after their last events dispatched, the events
+// the previous stream delivered meanwhile are dropped by dispatch.
+func (m *Multiplexer) restart(conn *muxConnection) {
+	channels := make([]string, 0, len(conn.channels))
+	for ch := range conn.channels {
+		channels = append(channels, ch)
+	}
+	slices.Sort(channels)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := m.client.newRequest(ctx, channels)
+	if err != nil {
+		cancel()
+		m.failLocked(channels, err)
+		return
+	}
+
+	s := &muxStream{cancel: cancel, channels: channels}
+	if conn.stream != nil {
+		for _, ch := range conn.stream.channels {
+			if _, found := conn.lastEventIds[ch]; !found && slices.Contains(channels, ch) {
+				s.unresumed = append(s.unresumed, ch)
+			}
+		}
+	}
+	conn.streamed = len(channels)
+	conn.pending = s
+	if len(conn.lastEventIds) > 0 {
+		req.Header.Set("Last-Event-ID", nchanLastEventId(channels, conn.lastEventIds))
+	}
+
+	go func() {
+		err := m.client.stream(
+			req,
+			func() { m.established(conn, s) },
+			func() { m.reportGap(conn, s, channels) },
+			func(id string, data string) { m.dispatch(conn, s, id, data) },
+		)
+
+		if ctx.Err() == nil { // Not replaced or closed by us.
+			if err == nil {
+				err = errors.New("sse: stream ended")
+			}
+
+			m.mutex.Lock()
+			m.failLocked(channels, err)
+			if conn.pending == s {
+				conn.pending = nil
+				m.applyLater(conn)
+			}
+			m.mutex.Unlock()
+		}
+	}()
+}
+
+// established replaces the stream of the connection with its pending replacement.
+func (m *Multiplexer) established(conn *muxConnection, s *muxStream) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if conn.pending != s {
+		return
+	}
+	conn.pending = nil
+	conn.replace(s)
+	m.applyLater(conn)
+
+	// The replacement started with the newest events of these channels, the replaced stream may not have
+	// delivered the ones before yet.
+	for _, ch := range s.unresumed {
+		if m.assignments[ch] != conn {
+			continue
+		}
+		for sub := range m.subscribers[ch] {
+			sub.pushGap(ErrNotResumable)
+		}
+	}
+}
+
+// reportGap tells the subscribers of the channels still on the connection that events may have been lost.
+func (m *Multiplexer) reportGap(conn *muxConnection, s *muxStream, channels []string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if conn.stream != s {
+		return // Replaced, the events are delivered by the current stream.
+	}
+
+	for _, ch := range channels {
+		if m.assignments[ch] != conn {
+			continue
+		}
+		for sub := range m.subscribers[ch] {
+			sub.pushGap(ErrNotResumable)
+		}
+	}
+}
+
+// failLocked reports the error to the subscribers of the channels, like Client.Connect does when the stream ends.
+func (m *Multiplexer) failLocked(channels []string, err error) {
+	for _, ch := range channels {
+		for sub := range m.subscribers[ch] {
+			sub.finish(err)
+		}
+	}
+}
+
+// dispatch routes the event to the subscribers of its game, if the game's channel is still on the connection.
+// Events of replaced streams are dropped, and so are the events of the current stream that its predecessor
+// already dispatched.
+func (m *Multiplexer) dispatch(conn *muxConnection, s *muxStream, id string, data string) {
+	m.mutex.Lock()
+	current, dispatched := conn.stream == s, false
+	if published, positions, ok := parseNchanMessageId(id, s.channels); current && ok {
+		last, found := conn.lastEventIds[published]
+		dispatched = found && !positions[published].after(last)
+
+		// The stream delivers in order, so the other channels are at least at their positions as well.
+		for ch, position := range positions {
+			if last, found := conn.lastEventIds[ch]; m.assignments[ch] == conn && (!found || position.after(last)) {
+				conn.lastEventIds[ch] = position
+			}
+		}
+	}
+	m.mutex.Unlock()
+	if !current || dispatched {
+		return
+	}
+
+	event, payload, err := ParseEvent(data)
+	skipped := event == nil && err == nil // Unknown events are routed nonetheless, to be recorded.
+
+	var routing struct {
+		GameId string `json:"gameId"`
+	}
+	if json.Unmarshal(payload, &routing) != nil || routing.GameId == "" {
+		if !skipped {
+			unroutableEventsCounter.Inc()
+		}
+		return
+	}
+
+	sseCh := gameChannelPrefix + routing.GameId
+	m.mutex.Lock()
+	assigned := m.assignments[sseCh] == conn // Otherwise it was removed and may have been added to another connection.
+	var subs []*subscriber
+	if assigned && !skipped {
+		for sub := range m.subscribers[sseCh] {
+			subs = append(subs, sub)
+		}
+	}
+	m.mutex.Unlock()
+	if assigned {
+		m.client.options.Recorder.Record(sseCh, id, data)
+	}
+
+	// Pushing may block, see BackpressureBlock.
+	for _, sub := range subs {
+		if err != nil {
+			sub.pushGap(err)
+		} else {
+			sub.push(ConnectChannelResult{Event: event})
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package sse
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testNchan is a minimal Nchan subscriber endpoint that supports multi-channel subscribe and Last-Event-ID.
+// The message IDs are Nchan-style, see nchanMessageId, with the number of messages published to a channel
+// as its time.
+type testNchan struct {
+	server       *httptest.Server
+	subscribers  map[chan string][]string      // The channels of each open connection.
+	held         map[chan string]chan struct{} // Connections that don't deliver until the channel is closed.
+	history      []testMessage
+	positions    map[string]int // The number of messages published to each channel.
+	lastEventIds []string       // The Last-Event-ID header of every request.
+	mutex        sync.Mutex
+}
+
+type testMessage struct {
+	channel   string
+	data      string
+	positions map[string]int // Of all channels, after publishing the message.
+}
+
+// event formats the message for a subscription of the channels.
+func (msg testMessage) event(channels []string) string {
+	entries := make([]string, len(channels))
+	for i, ch := range channels {
+		entries[i] = fmt.Sprintf("%d:0", msg.positions[ch])
+		if ch == msg.channel && len(channels) > 1 {
+			entries[i] = fmt.Sprintf("%d:[0]", msg.positions[ch])
+		}
+	}
+
+	return fmt.Sprintf("id: %s\ndata: %s\n\n", strings.Join(entries, ","), msg.data)
+}
+
+func newTestNchan(t *testing.T) *testNchan {
+	n := &testNchan{
+		subscribers: make(map[chan string][]string),
+		held:        make(map[chan string]chan struct{}),
+		positions:   make(map[string]int),
+	}
+	n.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		channels := strings.Split(r.URL.Query().Get("id"), ",")
+		messages := make(chan string, 16)
+		n.mutex.Lock()
+		n.subscribers[messages] = channels
+		n.lastEventIds = append(n.lastEventIds, r.Header.Get("Last-Event-ID"))
+		if resume := resumePositions(r.Header.Get("Last-Event-ID"), channels); resume != nil {
+			for _, msg := range n.history {
+				if from, ok := resume[msg.channel]; ok && from >= 0 && msg.positions[msg.channel] > from {
+					messages <- msg.event(channels)
+				}
+			}
+		}
+		n.mutex.Unlock()
+		defer func() {
+			n.mutex.Lock()
+			delete(n.subscribers, messages)
+			delete(n.held, messages)
+			n.mutex.Unlock()
+		}()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case event := <-messages:
+				n.mutex.Lock()
+				held := n.held[messages]
+				n.mutex.Unlock()
+				if held != nil {
+					select {
+					case <-held:
+					case <-r.Context().Done():
+						return
+					}
+				}
+				_, _ = fmt.Fprint(w, event)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	t.Cleanup(func() {
+		n.server.CloseClientConnections()
+		n.server.Close()
+	})
+
+	return n
+}
+
+// resumePositions parses the Last-Event-ID of a subscription to the channels, -1 means only newer messages.
+// It returns nil without a valid ID.
+func resumePositions(lastEventId string, channels []string) map[string]int {
+	entries := strings.Split(lastEventId, ",")
+	if lastEventId == "" || len(entries) != len(channels) {
+		return nil
+	}
+
+	positions := make(map[string]int)
+	for i, entry := range entries {
+		position, _, _ := strings.Cut(entry, ":")
+		var err error
+		if positions[channels[i]], err = strconv.Atoi(position); err != nil {
+			return nil
+		}
+	}
+
+	return positions
+}
+
+// publish sends the data to the subscribers of the channel.
+func (n *testNchan) publish(channel string, data string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.positions[channel]++
+	msg := testMessage{channel: channel, data: data, positions: maps.Clone(n.positions)}
+	n.history = append(n.history, msg)
+	for messages, channels := range n.subscribers {
+		if slices.Contains(channels, channel) {
+			messages <- msg.event(channels)
+		}
+	}
+}
+
+// hold stops the open connections from delivering until release is called, as if they were slow.
+func (n *testNchan) hold() (release func()) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	held := make(chan struct{})
+	for messages := range n.subscribers {
+		n.held[messages] = held
+	}
+
+	return sync.OnceFunc(func() { close(held) })
+}
+
+// drop closes all open connections.
+func (n *testNchan) drop() {
+	n.server.CloseClientConnections()
+}
+
+func (n *testNchan) requestedLastEventIds() []string {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	return slices.Clone(n.lastEventIds)
+}
+
+// connections returns the sorted channels of every open connection.
+func (n *testNchan) connections() []string {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	var connections []string
+	for _, channels := range n.subscribers {
+		sorted := slices.Sorted(slices.Values(channels))
+		connections = append(connections, strings.Join(sorted, ","))
+	}
+	slices.Sort(connections)
+
+	return connections
+}
+
+func (n *testNchan) waitForConnections(t *testing.T, want ...string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !slices.Equal(n.connections(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("connections = %q; want %q", n.connections(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func receive(t *testing.T, results chan ConnectChannelResult) ConnectChannelResult {
+	t.Helper()
+
+	select {
+	case res := <-results:
+		return res
+	case <-time.After(5 * time.Second):
+		t.Fatal("no result received")
+		return ConnectChannelResult{}
+	}
+}
+
+func TestMultiplexerSharesConnectionAndRoutesByGame(t *testing.T) {
+	nchan := newTestNchan(t)
+	m := NewMultiplexer(NewClient(nchan.server.URL, ClientOptions{}), MultiplexerOptions{Debounce: time.Millisecond})
+	defer m.Close()
+
+	first, _ := m.Connect(context.Background(), "connect-four-1")
+	second, _ := m.Connect(context.Background(), "connect-four-2")
+	nchan.waitForConnections(t, "connect-four-1,connect-four-2")
+
+	nchan.publish("connect-four-2", `ConnectFour.GameWon:1:{"gameId":"2"}`)
+	nchan.publish("connect-four-1", `ConnectFour.GameDrawn:2:{"gameId":"1"}`)
+
+	if res := receive(t, first); res.Event != (GameDrawn{GameId: "1"}) {
+		t.Fatalf("first game received %+v; want GameDrawn", res)
+	}
+	if res := receive(t, second); res.Event != (GameWon{GameId: "2"}) {
+		t.Fatalf("second game received %+v; want GameWon", res)
+	}
+}
+
+func TestMultiplexerLimitsChannelsPerConnection(t *testing.T) {
+	nchan := newTestNchan(t)
+	m := NewMultiplexer(NewClient(nchan.server.URL, ClientOptions{}), MultiplexerOptions{MaxChannelsPerConnection: 1, Debounce: time.Millisecond})
+	defer m.Close()
+
+	_, _ = m.Connect(context.Background(), "connect-four-1")
+	_, _ = m.Connect(context.Background(), "connect-four-2")
+
+	nchan.waitForConnections(t, "connect-four-1", "connect-four-2")
+}
+
+func TestMultiplexerRemovesEndedSubscriptions(t *testing.T) {
+	nchan := newTestNchan(t)
+	m := NewMultiplexer(NewClient(nchan.server.URL, ClientOptions{}), MultiplexerOptions{Debounce: time.Millisecond})
+	defer m.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ended, _ := m.Connect(ctx, "connect-four-1")
+	runningCtx, cancelRunning := context.WithCancel(context.Background())
+	running, _ := m.Connect(runningCtx, "connect-four-2")
+	nchan.waitForConnections(t, "connect-four-1,connect-four-2")
+
+	cancel()
+	if _, ok := <-ended; ok {
+		t.Fatal("results of the ended subscription are open; want closed")
+	}
+
+	// The connection isn't restarted for the running game, the ended game's events are dropped.
+	nchan.publish("connect-four-1", `ConnectFour.GameWon:1:{"gameId":"1"}`)
+	nchan.publish("connect-four-2", `ConnectFour.GameAborted:2:{"gameId":"2"}`)
+	if res := receive(t, running); res.Event != (GameAborted{GameId: "2"}) {
+		t.Fatalf("running game received %+v; want GameAborted", res)
+	}
+	if n := len(nchan.requestedLastEventIds()); n != 1 {
+		t.Fatalf("%d requests; want 1", n)
+	}
+
+	cancelRunning()
+	nchan.waitForConnections(t)
+}
+
+func TestMultiplexerCompactsMostlyEndedConnections(t *testing.T) {
+	nchan := newTestNchan(t)
+	m := NewMultiplexer(NewClient(nchan.server.URL, ClientOptions{}), MultiplexerOptions{Debounce: time.Millisecond})
+	defer m.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, _ = m.Connect(ctx, "connect-four-1")
+	_, _ = m.Connect(ctx, "connect-four-2")
+	_, _ = m.Connect(context.Background(), "connect-four-3")
+	nchan.waitForConnections(t, "connect-four-1,connect-four-2,connect-four-3")
+
+	cancel()
+	nchan.waitForConnections(t, "connect-four-3")
+}
+
+func TestMultiplexerKeepsOtherChannelsSeparate(t *testing.T) {
+	nchan := newTestNchan(t)
+	m := NewMultiplexer(NewClient(nchan.server.URL, ClientOptions{}), MultiplexerOptions{Debounce: time.Millisecond})
+	defer m.Close()
+
+	_, _ = m.Connect(context.Background(), "connect-four-1")
+	lobby, _ := m.Connect(context.Background(), "lobby")
+	nchan.waitForConnections(t, "connect-four-1", "lobby")
+
+	nchan.publish("lobby", `ConnectFour.GameOpened:1:{"gameId":"3","playerId":"p","width":7,"height":6}`)
+	if res := receive(t, lobby); res.Event != (GameOpened{GameId: "3", PlayerId: "p", Width: 7, Height: 6}) {
+		t.Fatalf("lobby received %+v; want GameOpened", res)
+	}
+}
+
+func TestMultiplexerReportsGapToAllChannelsOfLostConnection(t *testing.T) {
+	nchan := newTestNchan(t)
+	m := NewMultiplexer(NewClient(nchan.server.URL, reconnectingOptions), MultiplexerOptions{Debounce: time.Millisecond})
+	defer m.Close()
+
+	first, _ := m.Connect(context.Background(), "connect-four-1")
+	second, _ := m.Connect(context.Background(), "connect-four-2")
+	nchan.waitForConnections(t, "connect-four-1,connect-four-2")
+	nchan.drop()
+
+	if res := receive(t, first); res.Event != (Gap{Channel: "connect-four-1", Cause: ErrNotResumable}) {
+		t.Fatalf("first game received %+v; want Gap", res)
+	}
+	if res := receive(t, second); res.Event != (Gap{Channel: "connect-four-2", Cause: ErrNotResumable}) {
+		t.Fatalf("second game received %+v; want Gap", res)
+	}
+}
+
+func TestMultiplexerResumesReplacedConnection(t *testing.T) {
+	nchan := newTestNchan(t)
+	m := NewMultiplexer(NewClient(nchan.server.URL, ClientOptions{}), MultiplexerOptions{Debounce: time.Millisecond})
+	defer m.Close()
+
+	first, _ := m.Connect(context.Background(), "connect-four-1")
+	nchan.waitForConnections(t, "connect-four-1")
+	nchan.publish("connect-four-1", `ConnectFour.PlayerMoved:1:{"gameId":"1","x":4,"y":6,"color":1}`)
+	receive(t, first)
+
+	// The event published during the replacement is delivered once, by either connection.
+	_, _ = m.Connect(context.Background(), "connect-four-2")
+	nchan.publish("connect-four-1", `ConnectFour.PlayerMoved:2:{"gameId":"1","x":4,"y":5,"color":2}`)
+	nchan.waitForConnections(t, "connect-four-1,connect-four-2")
+	nchan.publish("connect-four-1", `ConnectFour.GameAborted:3:{"gameId":"1"}`)
+
+	if res := receive(t, first); res.Event != (PlayerMoved{GameId: "1", X: 4, Y: 5, Color: 2}) {
+		t.Fatalf("first game received %+v; want the second move", res)
+	}
+	if res := receive(t, first); res.Event != (GameAborted{GameId: "1"}) {
+		t.Fatalf("first game received %+v; want GameAborted", res)
+	}
+	select {
+	case res := <-first:
+		t.Fatalf("first game received %+v; want nothing more", res)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if ids := nchan.requestedLastEventIds(); len(ids) != 2 || ids[0] != "" || ids[1] == "" {
+		t.Fatalf("requests with Last-Event-ID %q; want none, then the last one received", ids)
+	}
+}
+
+func TestMultiplexerResumesChannelsAcrossChannelChange(t *testing.T) {
+	nchan := newTestNchan(t)
+	m := NewMultiplexer(NewClient(nchan.server.URL, ClientOptions{}), MultiplexerOptions{Debounce: time.Millisecond})
+	defer m.Close()
+
+	first, _ := m.Connect(context.Background(), "connect-four-1")
+	second, _ := m.Connect(context.Background(), "connect-four-2")
+	nchan.waitForConnections(t, "connect-four-1,connect-four-2")
+	nchan.publish("connect-four-1", `ConnectFour.PlayerMoved:1:{"gameId":"1","x":4,"y":6,"color":1}`)
+	receive(t, first)
+	nchan.publish("connect-four-2", `ConnectFour.PlayerMoved:2:{"gameId":"2","x":1,"y":6,"color":1}`)
+	receive(t, second)
+
+	// The replacement resumes the channels separately, the move the old connection holds back is replayed.
+	release := nchan.hold()
+	defer release()
+	nchan.publish("connect-four-1", `ConnectFour.PlayerMoved:3:{"gameId":"1","x":4,"y":5,"color":2}`)
+	third, _ := m.Connect(context.Background(), "connect-four-3")
+	nchan.waitForConnections(t, "connect-four-1,connect-four-2,connect-four-3")
+	nchan.publish("connect-four-3", `ConnectFour.GameAborted:4:{"gameId":"3"}`)
+
+	if res := receive(t, first); res.Event != (PlayerMoved{GameId: "1", X: 4, Y: 5, Color: 2}) {
+		t.Fatalf("first game received %+v; want the second move", res)
+	}
+	if res := receive(t, third); res.Event != (GameAborted{GameId: "3"}) {
+		t.Fatalf("third game received %+v; want GameAborted", res)
+	}
+	release()
+	select {
+	case res := <-first:
+		t.Fatalf("first game received %+v; want nothing more", res)
+	case res := <-second:
+		t.Fatalf("second game received %+v; want nothing more", res)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if ids := nchan.requestedLastEventIds(); !slices.Equal(ids, []string{"", "1:0,1:0,-1:0"}) {
+		t.Fatalf("requests with Last-Event-ID %q; want none, then the positions of the channels", ids)
+	}
+}
+
+func TestMultiplexerReportsGapForChannelsReplacedWithoutEvent(t *testing.T) {
+	nchan := newTestNchan(t)
+	m := NewMultiplexer(NewClient(nchan.server.URL, ClientOptions{}), MultiplexerOptions{Debounce: time.Millisecond})
+	defer m.Close()
+
+	first, _ := m.Connect(context.Background(), "connect-four-1")
+	nchan.waitForConnections(t, "connect-four-1")
+
+	// Without an event, there's no position to resume from, the held back move is lost.
+	release := nchan.hold()
+	defer release()
+	nchan.publish("connect-four-1", `ConnectFour.PlayerMoved:1:{"gameId":"1","x":4,"y":6,"color":1}`)
+	_, _ = m.Connect(context.Background(), "connect-four-2")
+	nchan.waitForConnections(t, "connect-four-1,connect-four-2")
+
+	if res := receive(t, first); res.Event != (Gap{Channel: "connect-four-1", Cause: ErrNotResumable}) {
+		t.Fatalf("first game received %+v; want Gap", res)
+	}
+}
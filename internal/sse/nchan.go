@@ -0,0 +1,79 @@
+package sse
+
+import (
+	"strconv"
+	"strings"
+)
+
+// nchanMessageId is the position of a message in its channel. Nchan formats it as "<time>:<tag>", the time of
+// publishing in seconds and a counter of the messages published in the same second.
+// The messages of a multi-channel subscription have an entry per channel, in the order of the subscription,
+// e.g. "1700000000:0,1700000002:[1]". The entry in brackets is of the channel the message was published to,
+// the others are the positions of the other channels. Nchan resumes each channel after its entry in Last-Event-ID.
+type nchanMessageId struct {
+	time int64
+	tag  int64
+}
+
+// nchanNewest is the position of a channel without a message received, Nchan only sends the later messages.
+var nchanNewest = nchanMessageId{time: -1}
+
+func (id nchanMessageId) String() string {
+	return strconv.FormatInt(id.time, 10) + ":" + strconv.FormatInt(id.tag, 10)
+}
+
+func (id nchanMessageId) after(other nchanMessageId) bool {
+	return id.time > other.time || id.time == other.time && id.tag > other.tag
+}
+
+// parseNchanMessageId returns the channel of the subscription the message was published to,
+// and the positions of all channels.
+func parseNchanMessageId(id string, channels []string) (string, map[string]nchanMessageId, bool) {
+	entries := strings.Split(id, ",")
+	if len(entries) != len(channels) {
+		return "", nil, false
+	}
+
+	published := ""
+	positions := make(map[string]nchanMessageId, len(channels))
+	for i, entry := range entries {
+		timePart, tagPart, ok := strings.Cut(entry, ":")
+		if !ok {
+			return "", nil, false
+		} else if strings.HasPrefix(tagPart, "[") && strings.HasSuffix(tagPart, "]") {
+			published, tagPart = channels[i], tagPart[1:len(tagPart)-1]
+		}
+
+		publishedAt, err := strconv.ParseInt(timePart, 10, 64)
+		if err != nil {
+			return "", nil, false
+		}
+		tag, err := strconv.ParseInt(tagPart, 10, 64)
+		if err != nil {
+			return "", nil, false
+		}
+		positions[channels[i]] = nchanMessageId{time: publishedAt, tag: tag}
+	}
+
+	if published == "" && len(channels) > 1 {
+		return "", nil, false
+	} else if published == "" { // A single channel's ID has no brackets.
+		published = channels[0]
+	}
+
+	return published, positions, true
+}
+
+// nchanLastEventId resumes the channels from their positions, the channels without one from the newest message.
+func nchanLastEventId(channels []string, positions map[string]nchanMessageId) string {
+	entries := make([]string, len(channels))
+	for i, ch := range channels {
+		position, ok := positions[ch]
+		if !ok {
+			position = nchanNewest
+		}
+		entries[i] = position.String()
+	}
+
+	return strings.Join(entries, ",")
+}
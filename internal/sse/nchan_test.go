@@ -0,0 +1,39 @@
+package sse
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParseNchanMessageId(t *testing.T) {
+	channels := []string{"a", "b"}
+	tests := []struct {
+		id            string
+		channels      []string
+		wantPublished string
+		wantPositions map[string]nchanMessageId
+	}{
+		{"1700000000:2", channels[:1], "a", map[string]nchanMessageId{"a": {1700000000, 2}}},
+		{"1700000000:0,1700000002:[1]", channels, "b", map[string]nchanMessageId{"a": {1700000000, 0}, "b": {1700000002, 1}}},
+		{"1700000000:[0],0:0", channels, "a", map[string]nchanMessageId{"a": {1700000000, 0}, "b": {0, 0}}},
+		{"1700000000:0", channels, "", nil},
+		{"1700000000:0,1700000002:1", channels, "", nil},
+		{"1700000000,1:[0]", channels, "", nil},
+		{"x:0", channels[:1], "", nil},
+		{"", channels[:1], "", nil},
+	}
+	for _, tt := range tests {
+		published, positions, ok := parseNchanMessageId(tt.id, tt.channels)
+		if published != tt.wantPublished || !maps.Equal(positions, tt.wantPositions) || ok != (tt.wantPositions != nil) {
+			t.Errorf("parseNchanMessageId(%q) = %q, %v, %v; want %q, %v", tt.id, published, positions, ok, tt.wantPublished, tt.wantPositions)
+		}
+	}
+}
+
+func TestNchanLastEventIdResumesUnknownChannelsFromNewest(t *testing.T) {
+	positions := map[string]nchanMessageId{"a": {1700000000, 2}}
+
+	if id := nchanLastEventId([]string{"a", "b"}, positions); id != "1700000000:2,-1:0" {
+		t.Fatalf("nchanLastEventId = %q; want 1700000000:2,-1:0", id)
+	}
+}
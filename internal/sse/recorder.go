@@ -0,0 +1,79 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+	"time"
+)
+
+// RecordedEvent is a message as received from Nchan. Recordings are stored as one JSON object per line.
+type RecordedEvent struct {
+	Time    time.Time `json:"time"`
+	Channel string    `json:"channel"`
+	Id      string    `json:"id,omitempty"`
+	Data    string    `json:"data"`
+}
+
+// Recorder tees the raw messages of the chosen channels into a file, e.g. to step through a game with cmd/replay.
+type Recorder struct {
+	patterns []string
+	encoder  *json.Encoder
+	mutex    sync.Mutex
+}
+
+// NewRecorder writes the messages of the channels matching any of the patterns to w, see path.Match.
+// Without patterns, all channels are recorded. Failing writes don't affect the subscriptions.
+func NewRecorder(w io.Writer, patterns ...string) *Recorder {
+	return &Recorder{patterns: patterns, encoder: json.NewEncoder(w)}
+}
+
+// Record writes the message if the channel is chosen. It does nothing on a nil recorder.
+func (r *Recorder) Record(channel string, id string, data string) {
+	if r == nil || !r.records(channel) {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	_ = r.encoder.Encode(RecordedEvent{Time: time.Now(), Channel: channel, Id: id, Data: data})
+}
+
+func (r *Recorder) records(channel string) bool {
+	if len(r.patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range r.patterns {
+		if ok, _ := path.Match(pattern, channel); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReadRecording parses the output of a recorder.
+func ReadRecording(r io.Reader) ([]RecordedEvent, error) {
+	var events []RecordedEvent
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var event RecordedEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("recording line %d: %w", line, err)
+		}
+		events = append(events, event)
+	}
+
+	return events, scanner.Err()
+}
@@ -0,0 +1,47 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRecorderRecordsRawDataOfChosenChannels(t *testing.T) {
+	var recording bytes.Buffer
+	recorder := NewRecorder(&recording, "connect-four-1")
+	nchan := newTestNchan(t)
+	m := NewMultiplexer(NewClient(nchan.server.URL, ClientOptions{Recorder: recorder}), MultiplexerOptions{Debounce: time.Millisecond})
+
+	first, _ := m.Connect(context.Background(), "connect-four-1")
+	second, _ := m.Connect(context.Background(), "connect-four-2")
+	nchan.waitForConnections(t, "connect-four-1,connect-four-2")
+
+	nchan.publish("connect-four-1", `ConnectFour.Unknown:1:{"gameId":"1"}`)
+	nchan.publish("connect-four-2", `ConnectFour.GameDrawn:2:{"gameId":"2"}`)
+	nchan.publish("connect-four-1", `ConnectFour.GameDrawn:3:{"gameId":"1"}`)
+	receive(t, first)
+	receive(t, second)
+	m.Close()
+
+	events, err := ReadRecording(&recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("recorded %d events; want 2", len(events))
+	}
+	want := []RecordedEvent{
+		{Channel: "connect-four-1", Id: "1:[0],0:0", Data: `ConnectFour.Unknown:1:{"gameId":"1"}`},
+		{Channel: "connect-four-1", Id: "2:[0],1:0", Data: `ConnectFour.GameDrawn:3:{"gameId":"1"}`},
+	}
+	for i, event := range events {
+		if event.Time.IsZero() {
+			t.Errorf("recorded event %d without time", i)
+		}
+		event.Time = time.Time{}
+		if event != want[i] {
+			t.Errorf("recorded event %d = %+v; want %+v", i, event, want[i])
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package sse
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var overflowedEventsCounter = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "app_sse_overflowed_events_total",
+	Help: "The number of events dropped because the subscriber's buffer was full.",
+})
+
+var gapsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "app_sse_gaps_total",
+	Help: "The number of gaps reported to subscribers, by cause.",
+}, []string{"cause"})
+
+// Backpressure decides what happens to an event when the subscriber's buffer is full.
+type Backpressure string
+
+const (
+	// BackpressureBlock makes the stream wait for the subscriber. On a multiplexed connection,
+	// this holds up the other channels as well.
+	BackpressureBlock Backpressure = "block"
+	// BackpressureDrop drops the event and reports a Gap once the subscriber catches up.
+	BackpressureDrop Backpressure = "drop"
+)
+
+// subscriber queues the results, so that a subscriber busy with a move doesn't hold up the stream.
+type subscriber struct {
+	channel      string
+	bufferSize   int // 0 means no limit.
+	backpressure Backpressure
+	out          chan ConnectChannelResult
+	queue        []ConnectChannelResult
+	overflowed   bool          // Events were dropped, a Gap is due.
+	finished     bool          // The last result is queued.
+	notify       chan struct{} // Tells pump about new results.
+	space        chan struct{} // Closed and replaced whenever pump takes a result.
+	done         chan struct{}
+	syncClose    sync.Once
+	mutex        sync.Mutex
+}
+
+func newSubscriber(channel string, options ClientOptions) *subscriber {
+	sub := &subscriber{
+		channel:      channel,
+		bufferSize:   options.BufferSize,
+		backpressure: options.Backpressure,
+		out:          make(chan ConnectChannelResult),
+		notify:       make(chan struct{}, 1),
+		space:        make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	go sub.pump()
+
+	return sub
+}
+
+// push queues an event according to the backpressure. It may block, so it must not be called while holding locks.
+func (s *subscriber) push(res ConnectChannelResult) {
+	s.mutex.Lock()
+	for s.bufferSize > 0 && len(s.queue) >= s.bufferSize && !s.finished {
+		if s.backpressure == BackpressureDrop {
+			s.overflowed = true
+			s.mutex.Unlock()
+			overflowedEventsCounter.Inc()
+			return
+		}
+
+		space := s.space
+		s.mutex.Unlock()
+		select {
+		case <-space:
+		case <-s.done:
+			return
+		}
+		s.mutex.Lock()
+	}
+	s.enqueueLocked(res)
+	s.mutex.Unlock()
+}
+
+// pushGap queues a gap regardless of the buffer, gaps are rare and the subscriber has to learn about them.
+func (s *subscriber) pushGap(cause error) {
+	s.mutex.Lock()
+	s.enqueueLocked(ConnectChannelResult{Event: Gap{Channel: s.channel, Cause: cause}})
+	s.mutex.Unlock()
+}
+
+// finish queues the error as last result. The results are closed once it's taken.
+func (s *subscriber) finish(err error) {
+	s.mutex.Lock()
+	s.enqueueLocked(ConnectChannelResult{Error: err})
+	s.finished = true
+	s.mutex.Unlock()
+}
+
+func (s *subscriber) enqueueLocked(res ConnectChannelResult) {
+	if s.finished {
+		return
+	}
+	if gap, ok := res.Event.(Gap); ok {
+		gapsCounter.With(map[string]string{"cause": gapCause(gap.Cause)}).Inc()
+	}
+
+	s.queue = append(s.queue, res)
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscriber) pump() {
+	defer close(s.out)
+
+	for {
+		s.mutex.Lock()
+		if len(s.queue) == 0 {
+			finished := s.finished
+			s.mutex.Unlock()
+			if finished {
+				return
+			}
+			select {
+			case <-s.notify:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		res := s.queue[0]
+		s.queue = s.queue[1:]
+		if s.overflowed { // There's room now, the gap goes after the events that were queued before.
+			s.overflowed = false
+			s.enqueueLocked(ConnectChannelResult{Event: Gap{Channel: s.channel, Cause: ErrOverflow}})
+		}
+		close(s.space)
+		s.space = make(chan struct{})
+		s.mutex.Unlock()
+
+		select {
+		case s.out <- res:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *subscriber) close() {
+	s.syncClose.Do(func() { close(s.done) })
+}
+
+// Subscription queues the results of a subscription like Client does, for other transports.
+type Subscription struct {
+	sub *subscriber
+}
+
+// NewSubscription applies the BufferSize and the Backpressure of the options.
+func NewSubscription(channel string, options ClientOptions) *Subscription {
+	return &Subscription{sub: newSubscriber(channel, options)}
+}
+
+// Results are closed after the result of Finish is taken, or on Close.
+func (s *Subscription) Results() chan ConnectChannelResult {
+	return s.sub.out
+}
+
+// Push queues the event according to the backpressure. It may block until the subscriber catches up or Close.
+func (s *Subscription) Push(event any) {
+	s.sub.push(ConnectChannelResult{Event: event})
+}
+
+// PushGap queues a Gap with the cause, regardless of the buffer.
+func (s *Subscription) PushGap(cause error) {
+	s.sub.pushGap(cause)
+}
+
+// Finish queues the error as last result.
+func (s *Subscription) Finish(err error) {
+	s.sub.finish(err)
+}
+
+// Close drops the queued results, e.g. once nobody reads them anymore.
+func (s *Subscription) Close() {
+	s.sub.close()
+}
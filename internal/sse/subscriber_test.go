@@ -0,0 +1,101 @@
+package sse
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func waitForOverflows(t *testing.T, want float64) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(overflowedEventsCounter) < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("%v overflowed events; want %v", testutil.ToFloat64(overflowedEventsCounter), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestClientDropsEventsOfSlowSubscriberAndReportsGap(t *testing.T) {
+	nchan := newTestNchan(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	options := ClientOptions{BufferSize: 1, Backpressure: BackpressureDrop}
+	results, err := NewClient(nchan.server.URL, options).Connect(ctx, "connect-four-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nchan.waitForConnections(t, "connect-four-1")
+
+	// At most one is handed out and one buffered, so the third is dropped at the latest.
+	overflows := testutil.ToFloat64(overflowedEventsCounter)
+	nchan.publish("connect-four-1", `ConnectFour.GameOpened:1:{"gameId":"1"}`)
+	nchan.publish("connect-four-1", `ConnectFour.PlayerJoined:2:{"gameId":"1"}`)
+	nchan.publish("connect-four-1", `ConnectFour.GameDrawn:3:{"gameId":"1"}`)
+	waitForOverflows(t, overflows+1)
+
+	if res := receive(t, results); res.Event != (GameOpened{GameId: "1"}) {
+		t.Fatalf("received %+v; want GameOpened", res)
+	}
+	res := receive(t, results)
+	if res.Event == (PlayerJoined{GameId: "1"}) { // Unless it was dropped as well.
+		res = receive(t, results)
+	}
+	if res.Event != (Gap{Channel: "connect-four-1", Cause: ErrOverflow}) {
+		t.Fatalf("received %+v; want Gap", res)
+	}
+
+	nchan.publish("connect-four-1", `ConnectFour.GameWon:4:{"gameId":"1"}`)
+	if res := receive(t, results); res.Event != (GameWon{GameId: "1"}) {
+		t.Fatalf("received %+v after the gap; want GameWon", res)
+	}
+}
+
+func TestClientBlocksStreamForSlowSubscriber(t *testing.T) {
+	nchan := newTestNchan(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	options := ClientOptions{BufferSize: 1, Backpressure: BackpressureBlock}
+	results, err := NewClient(nchan.server.URL, options).Connect(ctx, "connect-four-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nchan.waitForConnections(t, "connect-four-1")
+
+	nchan.publish("connect-four-1", `ConnectFour.GameOpened:1:{"gameId":"1"}`)
+	nchan.publish("connect-four-1", `ConnectFour.PlayerJoined:2:{"gameId":"1"}`)
+	nchan.publish("connect-four-1", `ConnectFour.GameDrawn:3:{"gameId":"1"}`)
+	time.Sleep(10 * time.Millisecond) // Give the stream the chance to drop something.
+
+	for _, want := range []any{GameOpened{GameId: "1"}, PlayerJoined{GameId: "1"}, GameDrawn{GameId: "1"}} {
+		if res := receive(t, results); res.Event != want {
+			t.Fatalf("received %+v; want %+v", res, want)
+		}
+	}
+}
+
+func TestMultiplexerDoesNotHoldUpOtherGamesForSlowSubscriber(t *testing.T) {
+	nchan := newTestNchan(t)
+	options := ClientOptions{BufferSize: 1, Backpressure: BackpressureDrop}
+	m := NewMultiplexer(NewClient(nchan.server.URL, options), MultiplexerOptions{Debounce: time.Millisecond})
+	defer m.Close()
+
+	_, _ = m.Connect(context.Background(), "connect-four-1") // Never read.
+	running, _ := m.Connect(context.Background(), "connect-four-2")
+	nchan.waitForConnections(t, "connect-four-1,connect-four-2")
+
+	for range 5 {
+		nchan.publish("connect-four-1", `ConnectFour.PlayerMoved:1:{"gameId":"1"}`)
+	}
+	nchan.publish("connect-four-2", `ConnectFour.GameWon:2:{"gameId":"2"}`)
+
+	if res := receive(t, running); res.Event != (GameWon{GameId: "2"}) {
+		t.Fatalf("second game received %+v; want GameWon", res)
+	}
+}
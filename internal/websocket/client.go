@@ -0,0 +1,214 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gaming-platform/connect-four-bot/internal/sse"
+	externalwebsocket "github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var reconnectsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "app_websocket_reconnects_total",
+	Help: "The number of reconnected websockets, by whether they resumed from the last event ID.",
+}, []string{"resumed"})
+
+// metaSubprotocol makes Nchan prefix every message with its ID, which is needed to resume.
+const metaSubprotocol = "ws+meta.nchan"
+
+const defaultReconnectInterval = 500 * time.Millisecond
+
+type ClientOptions struct {
+	// MaxReconnects limits the consecutive failed attempts to reconnect a lost websocket. 0 disables reconnecting.
+	MaxReconnects int
+	// ReconnectInterval is the initial wait before reconnecting, it grows with every failed attempt.
+	// 0 means 500ms.
+	ReconnectInterval time.Duration
+	// BufferSize limits the events queued for a subscriber that doesn't keep up. 0 means no limit.
+	BufferSize int
+	// Backpressure applies when the buffer is full, see sse.ClientOptions.
+	Backpressure sse.Backpressure
+	// Recorder, if set, gets the raw messages of all subscriptions.
+	Recorder *sse.Recorder
+}
+
+// Client subscribes to channels over Nchan's websocket endpoint. It behaves like sse.Client:
+// a lost connection is resumed from the last event ID, or reported as sse.Gap if there's none,
+// and the results are queued with the same backpressure.
+type Client struct {
+	NchanSubUrl string
+	options     ClientOptions
+	dialer      *externalwebsocket.Dialer
+}
+
+// NewClient accepts the same URL as sse.NewClient, http and https are replaced by ws and wss.
+func NewClient(nchanSubUrl string, options ClientOptions) *Client {
+	if options.ReconnectInterval <= 0 {
+		options.ReconnectInterval = defaultReconnectInterval
+	}
+
+	return &Client{
+		NchanSubUrl: nchanSubUrl,
+		options:     options,
+		dialer: &externalwebsocket.Dialer{
+			Proxy:            http.ProxyFromEnvironment,
+			HandshakeTimeout: 10 * time.Second,
+			Subprotocols:     []string{metaSubprotocol},
+		},
+	}
+}
+
+func (c *Client) Connect(ctx context.Context, wsCh string) (chan sse.ConnectChannelResult, error) {
+	wsUrl, err := c.url(wsCh)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := sse.NewSubscription(wsCh, sse.ClientOptions{
+		BufferSize:   c.options.BufferSize,
+		Backpressure: c.options.Backpressure,
+	})
+	context.AfterFunc(ctx, sub.Close)
+	go (func() {
+		sub.Finish(c.run(ctx, wsUrl, wsCh, sub))
+	})()
+
+	return sub.Results(), nil
+}
+
+func (c *Client) url(wsCh string) (string, error) {
+	u, err := url.Parse(c.NchanSubUrl)
+	if err != nil {
+		return "", err
+	}
+
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	}
+
+	return u.String() + "?id=" + wsCh, nil
+}
+
+// run reads the messages and reconnects until ctx is done or the reconnects are exhausted.
+func (c *Client) run(ctx context.Context, wsUrl string, wsCh string, sub *sse.Subscription) error {
+	var lastEventId string
+	connected := false
+	failures := 0
+
+	for {
+		err := c.read(ctx, wsUrl, wsCh, &lastEventId, func() {
+			if connected {
+				reconnectsCounter.With(map[string]string{"resumed": strconv.FormatBool(lastEventId != "")}).Inc()
+				if lastEventId == "" {
+					sub.PushGap(sse.ErrNotResumable)
+				}
+			}
+			connected = true
+			failures = 0
+		}, func(event any, err error) {
+			if err != nil { // The event is skipped, like events missed while the connection was down.
+				sub.PushGap(err)
+			} else {
+				sub.Push(event)
+			}
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		failures++
+		if failures > c.options.MaxReconnects {
+			return err
+		}
+
+		wait := time.Duration(float64(c.options.ReconnectInterval) * math.Pow(1.5, float64(failures-1)))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// read calls connected once the websocket is established, and handle for every known event.
+// It returns when the connection ends.
+func (c *Client) read(
+	ctx context.Context,
+	wsUrl string,
+	wsCh string,
+	lastEventId *string,
+	connected func(),
+	handle func(event any, err error),
+) error {
+	header := http.Header{}
+	if *lastEventId != "" {
+		header.Set("Last-Event-ID", *lastEventId)
+	}
+
+	conn, _, err := c.dialer.DialContext(ctx, wsUrl, header)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	connected()
+
+	// ReadMessage doesn't take a context.
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		data := message
+		if conn.Subprotocol() == metaSubprotocol {
+			var id string
+			id, data, err = parseMeta(message)
+			if err != nil {
+				return err
+			}
+			*lastEventId = id
+		}
+
+		c.options.Recorder.Record(wsCh, *lastEventId, string(data))
+
+		event, _, err := sse.ParseEvent(string(data))
+		if event == nil && err == nil {
+			continue
+		}
+
+		handle(event, err)
+	}
+}
+
+// parseMeta splits a message of the meta subprotocol into the message ID and the data.
+// The headers are separated from the data by an empty line, like in HTTP.
+func parseMeta(message []byte) (string, []byte, error) {
+	headers, data, ok := bytes.Cut(message, []byte("\n\n"))
+	if !ok {
+		return "", nil, errors.New("websocket: message without meta headers")
+	}
+
+	var id string
+	for _, line := range strings.Split(string(headers), "\n") {
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "id") {
+			id = strings.TrimSpace(value)
+		}
+	}
+
+	return id, data, nil
+}
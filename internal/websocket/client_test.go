@@ -0,0 +1,266 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gaming-platform/connect-four-bot/internal/sse"
+	externalwebsocket "github.com/gorilla/websocket"
+)
+
+// testNchan serves a single channel over websockets. Messages published while no one listens are
+// only delivered to a client resuming with Last-Event-ID.
+type testNchan struct {
+	server       *httptest.Server
+	conns        []*externalwebsocket.Conn
+	history      []string
+	lastEventIds []string // The Last-Event-ID header of every request.
+	meta         bool     // Whether the meta subprotocol is offered.
+	mutex        sync.Mutex
+}
+
+func newTestNchan(t *testing.T, meta bool) *testNchan {
+	n := &testNchan{meta: meta}
+	upgrader := externalwebsocket.Upgrader{}
+	if meta {
+		upgrader.Subprotocols = []string{metaSubprotocol}
+	}
+
+	n.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		n.mutex.Lock()
+		n.conns = append(n.conns, conn)
+		n.lastEventIds = append(n.lastEventIds, r.Header.Get("Last-Event-ID"))
+		var lastEventId int
+		_, _ = fmt.Sscan(r.Header.Get("Last-Event-ID"), &lastEventId)
+		if lastEventId > 0 {
+			for i, data := range n.history[lastEventId:] {
+				_ = conn.WriteMessage(externalwebsocket.TextMessage, n.format(lastEventId+i+1, data))
+			}
+		}
+		n.mutex.Unlock()
+
+		for { // Until the connection is closed.
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(n.server.Close)
+
+	return n
+}
+
+func (n *testNchan) format(id int, data string) []byte {
+	if !n.meta {
+		return []byte(data)
+	}
+
+	return fmt.Appendf(nil, "id: %d\ncontent-type: text/plain\n\n%s", id, data)
+}
+
+func (n *testNchan) publish(data string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.history = append(n.history, data)
+	for _, conn := range n.conns {
+		_ = conn.WriteMessage(externalwebsocket.TextMessage, n.format(len(n.history), data))
+	}
+}
+
+// drop closes all open connections.
+func (n *testNchan) drop() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	for _, conn := range n.conns {
+		_ = conn.Close()
+	}
+	n.conns = nil
+}
+
+func (n *testNchan) waitForConnections(t *testing.T, count int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n.mutex.Lock()
+		connections := len(n.conns)
+		n.mutex.Unlock()
+		if connections == count {
+			return
+		} else if time.Now().After(deadline) {
+			t.Fatalf("%d connections; want %d", connections, count)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (n *testNchan) requestedLastEventIds() []string {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	return slices.Clone(n.lastEventIds)
+}
+
+func receive(t *testing.T, results chan sse.ConnectChannelResult) sse.ConnectChannelResult {
+	t.Helper()
+
+	select {
+	case res := <-results:
+		return res
+	case <-time.After(5 * time.Second):
+		t.Fatal("no result received")
+		return sse.ConnectChannelResult{}
+	}
+}
+
+var reconnectingOptions = ClientOptions{MaxReconnects: 5, ReconnectInterval: 50 * time.Millisecond}
+
+func TestClientResumesFromLastEventId(t *testing.T) {
+	nchan := newTestNchan(t, true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results, err := NewClient(nchan.server.URL, reconnectingOptions).Connect(ctx, "connect-four-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nchan.waitForConnections(t, 1)
+	nchan.publish(`ConnectFour.GameDrawn:1:{"gameId":"1"}`)
+	if res := receive(t, results); res.Event != (sse.GameDrawn{GameId: "1"}) {
+		t.Fatalf("received %+v; want GameDrawn", res)
+	}
+
+	nchan.drop()
+	nchan.publish(`ConnectFour.GameWon:2:{"gameId":"1"}`) // Published while disconnected.
+
+	if res := receive(t, results); res.Event != (sse.GameWon{GameId: "1"}) {
+		t.Fatalf("received %+v after reconnecting; want GameWon", res)
+	}
+	if ids := nchan.requestedLastEventIds(); !slices.Equal(ids, []string{"", "1"}) {
+		t.Fatalf("requests with Last-Event-ID %q; want none, then 1", ids)
+	}
+}
+
+func TestClientReportsGapWithoutLastEventId(t *testing.T) {
+	nchan := newTestNchan(t, false) // Without the meta subprotocol, there are no event IDs.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results, err := NewClient(nchan.server.URL, reconnectingOptions).Connect(ctx, "connect-four-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nchan.waitForConnections(t, 1)
+	nchan.publish(`ConnectFour.GameDrawn:1:{"gameId":"1"}`)
+	receive(t, results)
+	nchan.drop()
+
+	if res := receive(t, results); res.Event != (sse.Gap{Channel: "connect-four-1", Cause: sse.ErrNotResumable}) {
+		t.Fatalf("received %+v after reconnecting; want Gap", res)
+	}
+}
+
+func TestClientFailsAfterReconnectsAreExhausted(t *testing.T) {
+	nchan := newTestNchan(t, true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results, err := NewClient(nchan.server.URL, ClientOptions{MaxReconnects: 1, ReconnectInterval: time.Millisecond}).
+		Connect(ctx, "connect-four-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nchan.waitForConnections(t, 1)
+	nchan.server.Close()
+	nchan.drop()
+
+	if res := receive(t, results); res.Error == nil {
+		t.Fatalf("received %+v after the server went away; want an error", res)
+	}
+	if _, ok := <-results; ok {
+		t.Fatal("results are open after the error; want closed")
+	}
+}
+
+func TestClientStopsWhenCancelledWithUnreadResults(t *testing.T) {
+	nchan := newTestNchan(t, true)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	_, err := NewClient(nchan.server.URL, reconnectingOptions).Connect(ctx, "connect-four-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nchan.waitForConnections(t, 1)
+	for i := range 3 { // More than the results can buffer.
+		nchan.publish(fmt.Sprintf(`ConnectFour.GameDrawn:%d:{"gameId":"1"}`, i+1))
+	}
+	cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for clientRunning() {
+		if time.Now().After(deadline) {
+			t.Fatal("the client is still running after cancellation; want it stopped")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestClientDropsEventsForSlowSubscribers(t *testing.T) {
+	nchan := newTestNchan(t, true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	options := ClientOptions{BufferSize: 1, Backpressure: sse.BackpressureDrop}
+	results, err := NewClient(nchan.server.URL, options).Connect(ctx, "connect-four-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nchan.waitForConnections(t, 1)
+	for i := range 5 {
+		nchan.publish(fmt.Sprintf(`ConnectFour.GameDrawn:%d:{"gameId":"1"}`, i+1))
+	}
+	time.Sleep(50 * time.Millisecond) // Let the client read ahead of the subscriber.
+
+	for {
+		res := receive(t, results)
+		if gap, ok := res.Event.(sse.Gap); ok && gap.Cause == sse.ErrOverflow {
+			return
+		} else if res.Event != (sse.GameDrawn{GameId: "1"}) {
+			t.Fatalf("received %+v; want GameDrawn until the Gap", res)
+		}
+	}
+}
+
+// clientRunning reports whether any goroutine is in the Client.
+func clientRunning() bool {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+
+	return bytes.Contains(buf, []byte("internal/websocket.(*Client)"))
+}
+
+func TestParseMeta(t *testing.T) {
+	id, data, err := parseMeta([]byte("id: 1700000000:0\ncontent-type: text/plain\n\nConnectFour.GameWon:1:{}"))
+	if err != nil || id != "1700000000:0" || string(data) != "ConnectFour.GameWon:1:{}" {
+		t.Fatalf("parseMeta = %q, %q, %v; want the ID and the data", id, data, err)
+	}
+
+	if _, _, err := parseMeta([]byte("ConnectFour.GameWon:1:{}")); err == nil {
+		t.Fatal("parseMeta without headers succeeded; want an error")
+	}
+}
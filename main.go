@@ -4,19 +4,27 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	chatv1 "github.com/gaming-platform/api/go/chat/v1"
+	connectfourv1 "github.com/gaming-platform/api/go/connectfour/v1"
+	identityv1 "github.com/gaming-platform/api/go/identity/v1"
+	"github.com/gaming-platform/connect-four-bot/internal/admin"
 	"github.com/gaming-platform/connect-four-bot/internal/bot"
 	"github.com/gaming-platform/connect-four-bot/internal/chat"
 	"github.com/gaming-platform/connect-four-bot/internal/config"
 	"github.com/gaming-platform/connect-four-bot/internal/connectfour"
 	"github.com/gaming-platform/connect-four-bot/internal/engine"
 	engine_marein "github.com/gaming-platform/connect-four-bot/internal/engine/marein"
+	engine_negamax "github.com/gaming-platform/connect-four-bot/internal/engine/negamax"
 	engine_random "github.com/gaming-platform/connect-four-bot/internal/engine/random"
 	"github.com/gaming-platform/connect-four-bot/internal/identity"
 	"github.com/gaming-platform/connect-four-bot/internal/rpcclient"
 	"github.com/gaming-platform/connect-four-bot/internal/sse"
+	"github.com/gaming-platform/connect-four-bot/internal/websocket"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/sync/errgroup"
 )
@@ -30,18 +38,50 @@ func main() {
 		log.Fatal(err)
 	}
 
-	rpcClient, err := rpcclient.NewAmqpRpcClient(
-		cfg.RabbitMqDsn,
-		cfg.RpcTimeout,
-		rpcclient.NewRouteMessagesToTransientRpcQueue(cfg.RpcExchange, "TransientRpc"),
-	)
+	rpcClient, err := newTransport(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if cfg.RpcRecordFile != "" {
+		recordFile, err := os.OpenFile(cfg.RpcRecordFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer recordFile.Close()
+		rpcClient = rpcclient.NewRecordingClient(rpcClient, recordFile)
+	}
+	circuitBreakerClient := rpcclient.NewCircuitBreakerClient(rpcClient, rpcclient.CircuitBreakerOptions{
+		FailureThreshold: cfg.RpcBreakerFailureThreshold,
+		OpenDuration:     cfg.RpcBreakerOpenDuration,
+		MaxConcurrency:   cfg.RpcMaxConcurrency,
+	})
+	rpcClient = rpcclient.NewRetryClient(circuitBreakerClient, rpcclient.RetryOptions{
+		AttemptTimeout: cfg.RpcTimeout,
+		MinBackoff:     100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		DefaultPolicy:  rpcclient.RetryPolicy{MaxAttempts: 3},
+		Policies: map[string]rpcclient.RetryPolicy{
+			// Queries and requests with an idempotency key can be retried freely.
+			connectfourv1.GetOpenGamesType:     {MaxAttempts: 5, Idempotent: true},
+			connectfourv1.GetGamesByPlayerType: {MaxAttempts: 5, Idempotent: true},
+			identityv1.GetBotByUsernameType:    {MaxAttempts: 5, Idempotent: true},
+			chatv1.WriteMessageType:            {MaxAttempts: 3, Idempotent: true},
+		},
+	})
 	rpcClient = rpcclient.NewPrometheusClient(rpcClient)
 	defer rpcClient.Close()
 
-	sseClient := sse.NewClient(cfg.NchanSubUrl)
+	var recorder *sse.Recorder
+	if cfg.SseRecordFile != "" {
+		recordFile, err := os.OpenFile(cfg.SseRecordFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer recordFile.Close()
+		recorder = sse.NewRecorder(recordFile, cfg.SseRecordChannels...)
+	}
+	events, closeEvents := newEventSource(cfg, recorder)
+	defer closeEvents()
 	chatSvc := chat.NewChatService(rpcClient)
 	botSvc := identity.NewBotService(rpcClient)
 	gameSvc := connectfour.NewGameService(rpcClient)
@@ -51,7 +91,9 @@ func main() {
 		log.Fatal(err)
 	}
 
+	pool := engine.NewPool(engine.PoolOptions{Workers: cfg.EngineWorkers, PonderShare: cfg.EnginePonderShare})
 	var calculateNextMove engine.CalculateNextMove
+	var ponder engine.Ponder
 	switch cfg.Level {
 	case 0:
 		calculateNextMove = engine_random.CalculateNextMove
@@ -63,22 +105,34 @@ func main() {
 		calculateNextMove = engine_marein.CreateCalculateNextMove(engine_marein.Options{
 			ForkCreationProbability: 100,
 		})
+	case 3:
+		negamax := engine_negamax.NewEngine(engine_negamax.Options{
+			MoveTime: cfg.EngineMoveTime,
+			Threads:  cfg.EngineThreads,
+			Budget:   pool,
+		})
+		calculateNextMove, ponder = negamax.CalculateNextMove, negamax.Ponder
 	default:
 		log.Fatalf("invalid level %d", cfg.Level)
 	}
 
-	resumingBot, err := bot.NewResumingBot(ctx, botId, calculateNextMove, sseClient, chatSvc, gameSvc)
+	calculateNextMove = pool.Wrap(calculateNextMove)
+	if ponder != nil {
+		ponder = pool.WrapPonder(ponder)
+	}
+
+	resumingBot, err := bot.NewResumingBot(ctx, botId, calculateNextMove, ponder, events, chatSvc, gameSvc)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	joiningBot, err := bot.NewJoiningBot(ctx, botId, calculateNextMove, cfg.JoinAfter, sseClient, chatSvc, gameSvc)
+	joiningBot, err := bot.NewJoiningBot(ctx, botId, calculateNextMove, ponder, cfg.JoinAfter, events, chatSvc, gameSvc)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	bots := [...]bot.Bot{
-		bot.NewOpeningBot(botId, calculateNextMove, sseClient, chatSvc, gameSvc),
+		bot.NewOpeningBot(botId, calculateNextMove, ponder, events, chatSvc, gameSvc),
 		joiningBot,
 		resumingBot,
 	}
@@ -94,6 +148,7 @@ func main() {
 	eg.Go(func() error {
 		mux := http.NewServeMux()
 		mux.Handle("/metrics", promhttp.Handler())
+		mux.Handle("/admin/", admin.NewHandler(circuitBreakerClient))
 		srv := &http.Server{Addr: ":80", Handler: mux}
 
 		go func() {
@@ -123,3 +178,51 @@ func requestBotId(ctx context.Context, botSvc *identity.BotService, username str
 		return bt.BotId, nil
 	}
 }
+
+func newTransport(cfg *config.Config) (rpcclient.RpcClient, error) {
+	if cfg.RpcTransport == "http" {
+		return rpcclient.NewHttpRpcClient(cfg.RpcHttpUrl, rpcclient.HttpOptions{
+			Encoding:            rpcclient.HttpEncoding(cfg.RpcHttpEncoding),
+			Timeout:             cfg.RpcHttpTimeout,
+			KeepAlive:           cfg.RpcHttpKeepAlive,
+			MaxIdleConnsPerHost: cfg.RpcHttpMaxIdleConns,
+			IdleConnTimeout:     cfg.RpcHttpIdleConnTimeout,
+		}), nil
+	}
+
+	return rpcclient.NewAmqpRpcClient(
+		cfg.RabbitMqDsn,
+		cfg.RpcTimeout,
+		rpcclient.NewRouteMessagesToTransientRpcQueue(cfg.RpcExchange, "TransientRpc"),
+		rpcclient.AmqpOptions{PublisherConfirms: cfg.RpcPublisherConfirms, Channels: cfg.RpcAmqpChannels},
+	)
+}
+
+// newEventSource returns the event source and the function to release it.
+func newEventSource(cfg *config.Config, recorder *sse.Recorder) (bot.EventSource, func()) {
+	if cfg.EventTransport == "websocket" {
+		return websocket.NewClient(cfg.NchanSubUrl, websocket.ClientOptions{
+			MaxReconnects:     cfg.SseMaxReconnects,
+			ReconnectInterval: cfg.SseReconnectInterval,
+			BufferSize:        cfg.SseBufferSize,
+			Backpressure:      sse.Backpressure(cfg.SseBackpressure),
+			Recorder:          recorder,
+		}), func() {}
+	}
+
+	multiplexer := sse.NewMultiplexer(
+		sse.NewClient(cfg.NchanSubUrl, sse.ClientOptions{
+			MaxReconnects:     cfg.SseMaxReconnects,
+			ReconnectInterval: cfg.SseReconnectInterval,
+			BufferSize:        cfg.SseBufferSize,
+			Backpressure:      sse.Backpressure(cfg.SseBackpressure),
+			Recorder:          recorder,
+		}),
+		sse.MultiplexerOptions{
+			MaxChannelsPerConnection: cfg.SseMaxChannelsPerConnection,
+			Debounce:                 cfg.SseSubscribeDebounce,
+		},
+	)
+
+	return multiplexer, multiplexer.Close
+}